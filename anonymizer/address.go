@@ -0,0 +1,120 @@
+package anonymizer
+
+import (
+	"github.com/andys/new_names/config"
+	"github.com/andys/new_names/db"
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// Address is a fake postal address whose street, city, state, postcode and
+// country belong together
+type Address struct {
+	Street   string
+	City     string
+	State    string
+	Postcode string
+	Country  string
+}
+
+// NewAddress generates an address in the given locale. An empty or unknown
+// locale gives an American address.
+func NewAddress(f *gofakeit.Faker, locale string) *Address {
+	return newAddress(f, locales[normalizeLocale(locale)])
+}
+
+func newAddress(f *gofakeit.Faker, l *localeData) *Address {
+	return addressAt(f, l, l.place(f))
+}
+
+// addressAt generates an address in a given place
+func addressAt(f *gofakeit.Faker, l *localeData, p place) *Address {
+	return &Address{
+		Street:   l.street(f),
+		City:     p.city,
+		State:    p.state,
+		Postcode: p.postcode,
+		Country:  l.homeCountry(),
+	}
+}
+
+// addressFields maps the field names usable in config to the address's values
+var addressFields = map[string]func(a *Address) string{
+	"street_address": func(a *Address) string { return a.Street },
+	"city":           func(a *Address) string { return a.City },
+	"state":          func(a *Address) string { return a.State },
+	"postcode":       func(a *Address) string { return a.Postcode },
+	"country":        func(a *Address) string { return a.Country },
+}
+
+// rowAddress generates the address shared by the row's address columns.
+// When any of them sets keep_country or keep_state, the row's original
+// country or state is kept and the rest of the address is made to fit it.
+// It returns nil when no locale has places in the kept country or no city
+// of the kept state is known, rather than put the row in the wrong place.
+func (s *rowState) rowAddress(cfg *config.Config, l *localeData) *Address {
+	var keepCountry, keepState bool
+	var country, state any
+	for _, col := range s.schema.Columns {
+		rule := cfg.Rule(s.schema.Name, col.Name)
+		if rule.Strategy != "address" {
+			continue
+		}
+		keepCountry = keepCountry || rule.KeepCountry
+		keepState = keepState || rule.KeepState
+		switch rule.Field {
+		case "country":
+			country = s.original[col.Name]
+		case "state":
+			state = s.original[col.Name]
+		}
+	}
+
+	f := rowFaker(cfg, s, "address", "", s.seedFor(cfg, "address"))
+	keepCountry = keepCountry && country != nil
+	if keepCountry {
+		locale := localeOf(country)
+		if locale == "" {
+			return nil
+		}
+		l = locales[locale]
+	}
+	var addr *Address
+	if keepState && state != nil {
+		p, ok := l.placeIn(f, stringValue(state))
+		if !ok {
+			return nil
+		}
+		addr = addressAt(f, l, p)
+	} else {
+		addr = newAddress(f, l)
+	}
+	if keepCountry {
+		addr.Country = stringValue(country)
+	}
+	if keepState && state != nil {
+		addr.State = stringValue(state)
+	}
+	return addr
+}
+
+// hasAddressField reports whether a column of the table uses the given address field
+func hasAddressField(cfg *config.Config, schema db.TableSchema, field string) bool {
+	for _, col := range schema.Columns {
+		if rule := cfg.Rule(schema.Name, col.Name); rule.Strategy == "address" && rule.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// addressValue fills a column from the row's address, leaving it unchanged
+// when the row has none
+func addressValue(_ *gofakeit.Faker, in *Input) (any, error) {
+	if in.state.address == nil {
+		in.state.address = in.state.rowAddress(in.Config, in.locale)
+	}
+	if in.state.address == nil {
+		return in.Value, nil
+	}
+	return addressFields[in.Rule.Field](in.state.address), nil
+}
@@ -0,0 +1,136 @@
+package anonymizer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/andys/new_names/config"
+	"github.com/andys/new_names/db"
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/frankban/quicktest"
+)
+
+var addressSchema = &db.TableSchema{
+	Name: "addresses",
+	Columns: []db.ColumnSchema{
+		{Name: "street", Type: "varchar"},
+		{Name: "city", Type: "varchar"},
+		{Name: "state", Type: "varchar"},
+		{Name: "postcode", Type: "varchar"},
+		{Name: "country", Type: "varchar"},
+	},
+}
+
+func addressConfig(keep config.ColumnRule) *config.Config {
+	rule := func(field string) config.ColumnRule {
+		r := keep
+		r.Strategy, r.Field = "address", field
+		return r
+	}
+	return &config.Config{
+		AnonymizeFields: map[string][]string{"addresses": {"street", "city", "state", "postcode", "country"}},
+		ColumnRules: map[string]map[string]config.ColumnRule{"addresses": {
+			"street":   rule("street_address"),
+			"city":     rule("city"),
+			"state":    rule("state"),
+			"postcode": rule("postcode"),
+			"country":  rule("country"),
+		}},
+	}
+}
+
+// placeOf finds the place a generated city belongs to
+func placeOf(places []place, city string) (place, bool) {
+	for _, p := range places {
+		if p.city == city {
+			return p, true
+		}
+	}
+	return place{}, false
+}
+
+func TestNewAddress_IsCoherent(t *testing.T) {
+	c := quicktest.New(t)
+	f := gofakeit.New(8)
+
+	for i := 0; i < 50; i++ {
+		a := NewAddress(f, "")
+		p, ok := placeOf(usPlaces, a.City)
+		c.Assert(ok, quicktest.IsTrue)
+		c.Assert(a.State, quicktest.Equals, p.state)
+		c.Assert(a.Postcode[:3], quicktest.Equals, p.postcode[:3])
+		c.Assert(a.Country, quicktest.Equals, "United States")
+	}
+}
+
+func TestAnonymize_AddressColumnsAgree(t *testing.T) {
+	c := quicktest.New(t)
+	cfg := addressConfig(config.ColumnRule{})
+	c.Assert(Validate(cfg, []db.TableSchema{*addressSchema}), quicktest.IsNil)
+
+	for i := 0; i < 20; i++ {
+		row := &Row{Schema: addressSchema, Data: map[string]interface{}{
+			"street": "12 Rue de Rivoli", "city": "Paris", "state": "Île-de-France", "postcode": "75001", "country": "France",
+		}}
+		c.Assert(Anonymize(row, cfg), quicktest.IsNil)
+		p, ok := placeOf(usPlaces, row.Data["city"].(string))
+		c.Assert(ok, quicktest.IsTrue, quicktest.Commentf("%v", row.Data))
+		c.Assert(row.Data["state"], quicktest.Equals, p.state)
+		c.Assert(strings.HasPrefix(row.Data["postcode"].(string), p.postcode[:3]), quicktest.IsTrue)
+		c.Assert(row.Data["country"], quicktest.Equals, "United States")
+	}
+}
+
+func TestAnonymize_AddressKeepsCountryAndState(t *testing.T) {
+	c := quicktest.New(t)
+	cfg := addressConfig(config.ColumnRule{KeepCountry: true, KeepState: true})
+
+	for i := 0; i < 20; i++ {
+		row := &Row{Schema: addressSchema, Data: map[string]interface{}{
+			"street": "1 Main St", "city": "El Paso", "state": "TX", "postcode": "79901", "country": "US",
+		}}
+		c.Assert(Anonymize(row, cfg), quicktest.IsNil)
+		c.Assert(row.Data["state"], quicktest.Equals, "TX")
+		c.Assert(row.Data["country"], quicktest.Equals, "US")
+		p, _ := placeOf(usPlaces, row.Data["city"].(string))
+		c.Assert(p.state, quicktest.Equals, "Texas", quicktest.Commentf("%v", row.Data))
+
+		row = &Row{Schema: addressSchema, Data: map[string]interface{}{
+			"street": "Hauptstraße 1", "city": "Fürth", "state": "Bayern", "postcode": "90762", "country": "Deutschland",
+		}}
+		c.Assert(Anonymize(row, cfg), quicktest.IsNil)
+		c.Assert(row.Data["country"], quicktest.Equals, "Deutschland")
+		p, _ = placeOf(locales["de"].places, row.Data["city"].(string))
+		c.Assert(p.state, quicktest.Equals, "Bayern", quicktest.Commentf("%v", row.Data))
+		c.Assert(row.Data["postcode"], quicktest.Matches, `\d{5}`)
+	}
+}
+
+func TestAnonymize_AddressUnknownPlaceUnchanged(t *testing.T) {
+	c := quicktest.New(t)
+	original := func(state, country string) map[string]interface{} {
+		return map[string]interface{}{"street": "1 High St", "city": "Somewhere", "state": state, "postcode": "12345", "country": country}
+	}
+	for _, data := range []map[string]interface{}{original("Île-de-France", "France"), original("Kent", "UK"), original("Tirol", "Austria")} {
+		row := &Row{Schema: addressSchema, Data: original(data["state"].(string), data["country"].(string))}
+		c.Assert(Anonymize(row, addressConfig(config.ColumnRule{KeepCountry: true})), quicktest.IsNil)
+		c.Assert(row.Data, quicktest.DeepEquals, data)
+	}
+
+	row := &Row{Schema: addressSchema, Data: original("Yukon", "US")}
+	c.Assert(Anonymize(row, addressConfig(config.ColumnRule{KeepState: true})), quicktest.IsNil)
+	c.Assert(row.Data, quicktest.DeepEquals, original("Yukon", "US"))
+}
+
+func TestValidate_Address(t *testing.T) {
+	c := quicktest.New(t)
+	schemas := []db.TableSchema{*addressSchema}
+	rules := func(rules map[string]config.ColumnRule) *config.Config {
+		return &config.Config{ColumnRules: map[string]map[string]config.ColumnRule{"addresses": rules}}
+	}
+
+	c.Assert(Validate(rules(map[string]config.ColumnRule{"city": {Strategy: "address", Field: "town"}}), schemas),
+		quicktest.ErrorMatches, `column addresses.city: unknown address field "town"`)
+	c.Assert(Validate(rules(map[string]config.ColumnRule{"city": {Strategy: "address", Field: "city", KeepState: true}}), schemas),
+		quicktest.ErrorMatches, `column addresses.city: keep_state needs a column using address.state`)
+}
@@ -1,13 +1,41 @@
 package anonymizer
 
 import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/andys/new_names/config"
 	"github.com/andys/new_names/db"
 	"github.com/brianvoe/gofakeit/v7"
 )
 
+// ErrBadValue marks a value of a row that can't be anonymized, such as text
+// that isn't valid JSON under a json rule, a date date_shift can't parse or
+// a token too long for its column. Only that row is lost, so callers can
+// count it as failed and carry on with the table. Config problems are never
+// ErrBadValue.
+var ErrBadValue = errors.New("value can't be anonymized")
+
+// valueError wraps an error caused by the data in a row, so it is an
+// ErrBadValue while keeping its own message
+type valueError struct{ error }
+
+func (e valueError) Is(target error) bool { return target == ErrBadValue }
+func (e valueError) Unwrap() error        { return e.error }
+
+// badValue marks err as caused by the value being anonymized
+func badValue(err error) error {
+	return valueError{err}
+}
+
 // Row represents a single row of data with its table schema
 type Row struct {
 	Schema *db.TableSchema
@@ -46,83 +74,652 @@ func toUint64(v any) uint64 {
 	return 0
 }
 
+// stringValue returns the textual form of a scanned database value
+func stringValue(v any) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case []byte:
+		return string(s)
+	case time.Time:
+		return s.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
+
+// fakerFor returns the faker used to generate a replacement for val.
+// When a key is configured, the faker is seeded from an HMAC of the generator
+// kind and the original value. Table and column names are deliberately left
+// out so that the same value maps to the same fake wherever it appears, which
+// keeps joins intact and stops re-runs from churning the destination.
+// Without a key the shared global faker is used.
+func fakerFor(cfg *config.Config, kind string, val any) *gofakeit.Faker {
+	if cfg.Key == "" {
+		return gofakeit.GlobalFaker
+	}
+	return seededFaker([]byte(cfg.Key), kind, val)
+}
+
+// rowFaker returns the faker for a choice made in a row, such as a column's
+// replacement. In a run with a seed but no key it is seeded from the seed
+// and where the choice is made: the table, the row as the destination shows
+// it (see placeKey) and place. Original values never go in, so the seed,
+// which is printed, reveals nothing about the source. Otherwise, or when
+// nothing public identifies the row, it is fakerFor(cfg, kind, val).
+func rowFaker(cfg *config.Config, state *rowState, kind, place string, val any) *gofakeit.Faker {
+	if cfg.Key != "" || cfg.Seed == nil || state == nil {
+		return fakerFor(cfg, kind, val)
+	}
+	key, ok := state.placeKey(cfg)
+	if !ok {
+		return fakerFor(cfg, kind, val)
+	}
+	return seededFaker(binary.BigEndian.AppendUint64(nil, *cfg.Seed), kind, key+"\x00"+place)
+}
+
+// secretStrategies combine the original value with their randomness:
+// date_shift adds a number of days and noise a percentage. Anyone able to
+// recompute that randomness could undo them, so they never draw from the
+// seed.
+var secretStrategies = map[string]bool{
+	"date_shift": true,
+	"noise":      true,
+}
+
+// seededFaker returns a faker seeded from an HMAC of kind and val under key
+func seededFaker(key []byte, kind string, val any) *gofakeit.Faker {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(kind))
+	mac.Write([]byte{0})
+	mac.Write([]byte(stringValue(val)))
+	seed := binary.BigEndian.Uint64(mac.Sum(nil))
+	if seed == 0 {
+		// gofakeit treats a zero seed as a request for a random one
+		seed = 1
+	}
+	return gofakeit.New(seed)
+}
+
+// guessKind picks a generator for a column from its type and, for text, its name
+func guessKind(col db.ColumnSchema) string {
+	lowerName := strings.ToLower(col.Name)
+	colType := strings.ToLower(col.Type)
+	switch logicalType(col) {
+	case db.TypeInteger:
+		return "integer"
+	case db.TypeDecimal:
+		return "decimal"
+	case db.TypeFloat:
+		return "float"
+	case db.TypeDate, db.TypeTimestamp:
+		return "date_shift"
+	case db.TypeTime:
+		if colType == "interval" {
+			return "interval"
+		}
+		return "time"
+	case db.TypeBool:
+		return "boolean"
+	case db.TypeUUID:
+		return "uuid"
+	case db.TypeJSON:
+		return "json"
+	case db.TypeEnum:
+		if colType == "set" {
+			return "set"
+		}
+		return "enum"
+	case db.TypeArray:
+		return "array"
+	case db.TypeGeometry:
+		return "geometry"
+	case db.TypeBinary:
+		if colType == "bit" {
+			return "bits"
+		}
+		return "placeholder"
+	}
+	if kind, ok := textKinds[colType]; ok {
+		return kind
+	}
+	switch {
+	case strings.Contains(lowerName, "email"):
+		return "email"
+	case strings.Contains(lowerName, "phone"):
+		return "phone"
+	case strings.Contains(lowerName, "name"):
+		return "name"
+	}
+	return "text"
+}
+
+// rowState holds what is shared between the columns of one row
+type rowState struct {
+	schema   *db.TableSchema
+	original map[string]any // The row's values before anonymization
+	person   *Person
+	address  *Address
+	draws    map[string]int // Column to the number of values generated for it, for seeded runs
+	place    *string        // The row's placeKey, once worked out
+}
+
+// seedFor joins the original values of every column in the row that uses
+// strategy, so row-level transformers can be seeded consistently in keyed mode
+func (s *rowState) seedFor(cfg *config.Config, strategy string) string {
+	var parts []string
+	for _, col := range s.schema.Columns {
+		if cfg.Rule(s.schema.Name, col.Name).Strategy == strategy {
+			parts = append(parts, stringValue(s.original[col.Name]))
+		}
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// rowKey identifies the row: by its ID when the table has one, otherwise by
+// all of its original values
+func (s *rowState) rowKey() string {
+	if s.schema.HasID {
+		return stringValue(s.original[s.schema.IDCol])
+	}
+	parts := make([]string, len(s.schema.Columns))
+	for i, col := range s.schema.Columns {
+		parts[i] = stringValue(s.original[col.Name])
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// placeKey identifies the row by what the destination shows anyway: the
+// table and the row's ID, or for tables without one the values of the
+// columns that aren't anonymized. It reports false when every column is.
+func (s *rowState) placeKey(cfg *config.Config) (string, bool) {
+	if s.place == nil {
+		anonymized := make(map[string]bool)
+		for _, column := range cfg.AnonymizeFields[s.schema.Name] {
+			anonymized[column] = true
+		}
+		parts := []string{s.schema.Name}
+		if s.schema.HasID && !anonymized[s.schema.IDCol] {
+			parts = append(parts, stringValue(s.original[s.schema.IDCol]))
+		} else {
+			for _, col := range s.schema.Columns {
+				if !anonymized[col.Name] {
+					parts = append(parts, col.Name+"="+stringValue(s.original[col.Name]))
+				}
+			}
+		}
+		place := ""
+		if len(parts) > 1 {
+			place = strings.Join(parts, "\x00")
+		}
+		s.place = &place
+	}
+	return *s.place, *s.place != ""
+}
+
+// draw names the next value generated for column in the row, so that each
+// element of an array or JSON document gets a faker of its own
+func (s *rowState) draw(column string) string {
+	if s.draws == nil {
+		s.draws = make(map[string]int)
+	}
+	s.draws[column]++
+	return column + "\x00" + strconv.Itoa(s.draws[column])
+}
+
+// builtinTransformers are the strategies that come with the anonymizer
+var builtinTransformers = map[string]TransformerFunc{
+	"integer":     integerValue,
+	"float":       floatValue,
+	"decimal":     decimalValue,
+	"boolean":     booleanValue,
+	"enum":        enumValue,
+	"set":         setValue,
+	"time":        timeValue,
+	"interval":    intervalValue,
+	"bits":        bitsValue,
+	"geometry":    geometryValue,
+	"array":       arrayValue,
+	"date":        dateValue,
+	"placeholder": placeholderValue,
+	"zero_fill":   zeroFillValue,
+	"truncate":    truncateValue,
+	"mac_address": func(f *gofakeit.Faker, _ *Input) (any, error) { return f.MacAddress(), nil },
+	"email":       func(f *gofakeit.Faker, _ *Input) (any, error) { return f.Email(), nil },
+	"phone":       func(f *gofakeit.Faker, in *Input) (any, error) { return in.locale.phone(f), nil },
+	"name": func(f *gofakeit.Faker, in *Input) (any, error) {
+		if in.locale == nil {
+			return f.Name(), nil
+		}
+		first := in.locale.firstName(f, f.RandomString([]string{"male", "female"}))
+		return in.locale.fullName(first, in.locale.lastName(f)), nil
+	},
+	"first_name": func(f *gofakeit.Faker, in *Input) (any, error) {
+		if in.locale == nil {
+			return f.FirstName(), nil
+		}
+		return in.locale.firstName(f, f.RandomString([]string{"male", "female"})).text, nil
+	},
+	"last_name":      func(f *gofakeit.Faker, in *Input) (any, error) { return in.locale.lastName(f).text, nil },
+	"username":       func(f *gofakeit.Faker, _ *Input) (any, error) { return f.Username(), nil },
+	"company":        func(f *gofakeit.Faker, _ *Input) (any, error) { return f.Company(), nil },
+	"street_address": func(f *gofakeit.Faker, in *Input) (any, error) { return in.locale.street(f), nil },
+	"city":           func(f *gofakeit.Faker, in *Input) (any, error) { return in.locale.place(f).city, nil },
+	"postcode":       func(f *gofakeit.Faker, in *Input) (any, error) { return in.locale.place(f).postcode, nil },
+	"country":        func(f *gofakeit.Faker, in *Input) (any, error) { return in.locale.countryName(f), nil },
+	"ipv4":           func(f *gofakeit.Faker, _ *Input) (any, error) { return f.IPv4Address(), nil },
+	"ipv6":           func(f *gofakeit.Faker, _ *Input) (any, error) { return f.IPv6Address(), nil },
+	"uuid":           func(f *gofakeit.Faker, _ *Input) (any, error) { return f.UUID(), nil },
+	"url":            func(f *gofakeit.Faker, _ *Input) (any, error) { return f.URL(), nil },
+	"lorem":          func(f *gofakeit.Faker, _ *Input) (any, error) { return f.LoremIpsumSentence(5), nil },
+	"text": func(f *gofakeit.Faker, in *Input) (any, error) {
+		if in.MaxLen >= 50 {
+			return f.Sentence(5), nil
+		}
+		return f.LetterN(uint(in.MaxLen)), nil
+	},
+	"null": func(_ *gofakeit.Faker, _ *Input) (any, error) { return nil, nil },
+	"constant": func(_ *gofakeit.Faker, in *Input) (any, error) {
+		return *in.Rule.Value, nil
+	},
+	"redact": func(_ *gofakeit.Faker, in *Input) (any, error) {
+		if in.Rule.Value != nil {
+			return *in.Rule.Value, nil
+		}
+		return redactedMarker, nil
+	},
+	"hash": func(_ *gofakeit.Faker, in *Input) (any, error) {
+		salt := in.Rule.Salt
+		if salt == "" {
+			salt = in.Config.Key
+		}
+		return HashValue(salt, stringValue(in.Value), in.Rule.Encoding), nil
+	},
+	"date_shift": func(f *gofakeit.Faker, in *Input) (any, error) {
+		maxDays := in.Rule.MaxDays
+		if maxDays == 0 {
+			maxDays = defaultShiftDays
+		}
+		var days int
+		if entity := in.state.original[in.Rule.Entity]; in.Rule.Entity != "" && entity != nil {
+			days = entityShiftDays(secretKey(in.Config), stringValue(entity), maxDays)
+		} else {
+			days = nonZeroOffset(f.Uint64(), maxDays)
+		}
+		return ShiftDate(in.Value, days)
+	},
+	"noise":      perturbValue,
+	"dictionary": dictionaryValue,
+	"shuffle":    shuffledValue,
+	"tokenize":   tokenizeValue,
+	"address":    addressValue,
+	"json": func(_ *gofakeit.Faker, in *Input) (any, error) {
+		return anonymizeJSON(in)
+	},
+	"scrub": func(_ *gofakeit.Faker, in *Input) (any, error) {
+		return scrubText(in)
+	},
+	"person": func(_ *gofakeit.Faker, in *Input) (any, error) {
+		if in.state.person == nil {
+			seed := in.state.seedFor(in.Config, "person")
+			in.state.person = newPerson(rowFaker(in.Config, in.state, "person", "", seed), in.locale)
+		}
+		return personValue(in.state.person, in.Rule.Field, in.Value), nil
+	},
+	"template": func(_ *gofakeit.Faker, in *Input) (any, error) {
+		return RenderTemplate(in.Rule.Template, in.Row), nil
+	},
+	"mask": func(f *gofakeit.Faker, in *Input) (any, error) {
+		masked := MaskFormat(f, stringValue(in.Value), MaskOptions{
+			KeepPrefix: in.Rule.KeepPrefix,
+			KeepSuffix: in.Rule.KeepSuffix,
+			KeepDomain: in.Rule.KeepDomain,
+		})
+		switch in.Value.(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+			// Keep integer columns numeric
+			if n, err := strconv.ParseInt(masked, 10, 64); err == nil {
+				return n, nil
+			}
+		}
+		return masked, nil
+	},
+}
+
+// hasColumn reports whether the table has a column with the given name
+func hasColumn(schema db.TableSchema, name string) bool {
+	for _, col := range schema.Columns {
+		if col.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// redactedMarker is written by the redact strategy when no value is configured
+const redactedMarker = "[REDACTED]"
+
+// fixedStrategies write the same value for every row, including NULL and blank ones
+var fixedStrategies = map[string]bool{
+	"null":     true,
+	"constant": true,
+	"redact":   true,
+}
+
+// Validate checks the anonymization rules in cfg against the source schemas
+func Validate(cfg *config.Config, schemas []db.TableSchema) error {
+	if err := checkLocale(cfg.Locale); err != nil {
+		return err
+	}
+	if err := checkEmptyPolicy(cfg.Empty, cfg.FillRate); err != nil {
+		return err
+	}
+	for _, schema := range schemas {
+		if _, err := columnOrder(&schema, cfg); err != nil {
+			return err
+		}
+		if err := validateLocale(schema, cfg.TableLocales[schema.Name].Locale, cfg.TableLocales[schema.Name].From); err != nil {
+			return fmt.Errorf("table %s: %w", schema.Name, err)
+		}
+		for _, col := range schema.Columns {
+			rule := cfg.Rule(schema.Name, col.Name)
+			if err := validateLocale(schema, rule.Locale, rule.LocaleFrom); err != nil {
+				return fmt.Errorf("column %s.%s: %w", schema.Name, col.Name, err)
+			}
+			for _, conds := range []config.Conditions{rule.When, rule.Unless} {
+				if err := validateConditions(schema, conds); err != nil {
+					return fmt.Errorf("column %s.%s: %w", schema.Name, col.Name, err)
+				}
+			}
+			if err := checkEmptyPolicy(rule.Empty, rule.FillRate); err != nil {
+				return fmt.Errorf("column %s.%s: %w", schema.Name, col.Name, err)
+			}
+			if rule.Strategy == "" {
+				continue
+			}
+			if err := validateRule(cfg, schema, col, rule); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateLocale checks a locale setting and the column it may be read from
+func validateLocale(schema db.TableSchema, locale, from string) error {
+	if err := checkLocale(locale); err != nil {
+		return err
+	}
+	if from != "" && !hasColumn(schema, from) {
+		return fmt.Errorf("locale column %s does not exist", from)
+	}
+	return nil
+}
+
+// validateRule checks the options of one rule applied to col
+func validateRule(cfg *config.Config, schema db.TableSchema, col db.ColumnSchema, rule config.ColumnRule) error {
+	t, ok := lookupTransformer(rule.Strategy)
+	if !ok {
+		return fmt.Errorf("column %s.%s: unknown anonymization strategy %q", schema.Name, col.Name, rule.Strategy)
+	}
+	if v, ok := t.(RuleValidator); ok {
+		if err := v.ValidateRule(col, rule); err != nil {
+			return fmt.Errorf("column %s.%s: %w", schema.Name, col.Name, err)
+		}
+	}
+	switch rule.Strategy {
+	case "null":
+		if !col.Nullable {
+			return fmt.Errorf("column %s.%s is NOT NULL and cannot use the null strategy", schema.Name, col.Name)
+		}
+	case "constant":
+		if rule.Value == nil {
+			return fmt.Errorf("column %s.%s: constant strategy requires a value", schema.Name, col.Name)
+		}
+	case "hash":
+		if rule.Salt == "" && cfg.Key == "" {
+			return fmt.Errorf("column %s.%s: hash strategy requires a salt or a key", schema.Name, col.Name)
+		}
+		if _, ok := hashEncodings[rule.Encoding]; !ok {
+			return fmt.Errorf("column %s.%s: unknown hash encoding %q", schema.Name, col.Name, rule.Encoding)
+		}
+		// Hashes are text, which a numeric, date or whole JSON column won't take
+		if logicalType(col) != db.TypeText && !isLeafColumn(col) {
+			return fmt.Errorf("column %s.%s: hash strategy needs a text column, not %s", schema.Name, col.Name, col.Type)
+		}
+	case "mask":
+		if rule.KeepPrefix < 0 || rule.KeepSuffix < 0 {
+			return fmt.Errorf("column %s.%s: keep_prefix and keep_suffix must not be negative", schema.Name, col.Name)
+		}
+	case "noise":
+		if rule.Percent < 0 {
+			return fmt.Errorf("column %s.%s: percent must not be negative", schema.Name, col.Name)
+		}
+		if rule.Min != nil && rule.Max != nil && *rule.Min > *rule.Max {
+			return fmt.Errorf("column %s.%s: min is greater than max", schema.Name, col.Name)
+		}
+	case "dictionary":
+		if rule.File == "" {
+			return fmt.Errorf("column %s.%s: dictionary strategy requires a file", schema.Name, col.Name)
+		}
+		if _, err := loadDictionary(cfg, rule); err != nil {
+			return fmt.Errorf("column %s.%s: %w", schema.Name, col.Name, err)
+		}
+	case "shuffle":
+		if !hasColumn(schema, col.Name) {
+			return fmt.Errorf("column %s.%s: shuffle strategy only applies to whole columns", schema.Name, col.Name)
+		}
+		if rule.Partition != "" && !hasColumn(schema, rule.Partition) {
+			return fmt.Errorf("column %s.%s: partition column %s does not exist", schema.Name, col.Name, rule.Partition)
+		}
+	case "tokenize":
+		if len(cfg.TokenKey) == 0 {
+			return fmt.Errorf("column %s.%s: tokenize strategy requires a token key file", schema.Name, col.Name)
+		}
+		if !tokenizable(col) {
+			return fmt.Errorf("column %s.%s: tokenize strategy needs a text column, not %s", schema.Name, col.Name, col.Type)
+		}
+		if col.MaxLength > 0 && col.MaxLength < tokenLength(1) {
+			return fmt.Errorf("column %s.%s: tokens need at least %d characters, the column holds %d", schema.Name, col.Name, tokenLength(1), col.MaxLength)
+		}
+	case "truncate":
+		if rule.Bytes <= 0 {
+			return fmt.Errorf("column %s.%s: truncate strategy requires a positive bytes length", schema.Name, col.Name)
+		}
+	case "enum", "set":
+		if len(col.EnumValues) == 0 {
+			return fmt.Errorf("column %s.%s: %s strategy needs a column with enum or set values", schema.Name, col.Name, rule.Strategy)
+		}
+	case "array":
+		if logicalType(col) != db.TypeArray {
+			return fmt.Errorf("column %s.%s: array strategy needs an array column, not %s", schema.Name, col.Name, col.Type)
+		}
+	case "person":
+		if _, ok := personFields[rule.Field]; !ok {
+			return fmt.Errorf("column %s.%s: unknown person field %q", schema.Name, col.Name, rule.Field)
+		}
+	case "address":
+		if _, ok := addressFields[rule.Field]; !ok {
+			return fmt.Errorf("column %s.%s: unknown address field %q", schema.Name, col.Name, rule.Field)
+		}
+		if rule.KeepCountry && !hasAddressField(cfg, schema, "country") {
+			return fmt.Errorf("column %s.%s: keep_country needs a column using address.country", schema.Name, col.Name)
+		}
+		if rule.KeepState && !hasAddressField(cfg, schema, "state") {
+			return fmt.Errorf("column %s.%s: keep_state needs a column using address.state", schema.Name, col.Name)
+		}
+	case "template":
+		if rule.Template == "" {
+			return fmt.Errorf("column %s.%s: template strategy requires a template", schema.Name, col.Name)
+		}
+		if err := checkTemplate(schema, rule.Template); err != nil {
+			return fmt.Errorf("column %s.%s: %w", schema.Name, col.Name, err)
+		}
+	case "json":
+		if len(rule.Paths) == 0 {
+			return fmt.Errorf("column %s.%s: json strategy requires paths", schema.Name, col.Name)
+		}
+		for _, path := range sortedPaths(rule.Paths) {
+			if _, err := parseJSONPath(path); err != nil {
+				return fmt.Errorf("column %s.%s: %w", schema.Name, col.Name, err)
+			}
+			leafRule := rule.Paths[path]
+			if leafRule.Strategy == "" {
+				return fmt.Errorf("column %s.%s: path %s requires a strategy", schema.Name, col.Name, path)
+			}
+			if err := validateRule(cfg, schema, leafColumn(col, path), leafRule); err != nil {
+				return err
+			}
+		}
+	case "scrub":
+		for _, name := range scrubPatternNames(rule) {
+			pattern, err := lookupScrubPattern(cfg, name)
+			if err != nil {
+				return fmt.Errorf("column %s.%s: %w", schema.Name, col.Name, err)
+			}
+			if err := validateRule(cfg, schema, scrubColumn(col, name), pattern.rule); err != nil {
+				return err
+			}
+		}
+	case "date_shift":
+		if rule.MaxDays < 0 {
+			return fmt.Errorf("column %s.%s: max_days must not be negative", schema.Name, col.Name)
+		}
+		if rule.Entity != "" && !hasColumn(schema, rule.Entity) {
+			return fmt.Errorf("column %s.%s: entity column %q does not exist", schema.Name, col.Name, rule.Entity)
+		}
+	}
+	return nil
+}
+
+// isEmpty reports whether val is NULL, a blank string or numerically zero
+func isEmpty(val any) bool {
+	switch v := val.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == ""
+	case []byte:
+		return len(bytes.TrimSpace(v)) == 0
+	case int, int8, int16, int32, int64:
+		return toInt64(v) == 0
+	case uint, uint8, uint16, uint32, uint64:
+		return toUint64(v) == 0
+	case float32:
+		return v == 0.0
+	case float64:
+		return v == 0.0
+	}
+	return false
+}
+
+// columnEmpty is isEmpty for a value of col. Binary values are only empty
+// when they have no bytes, as whitespace bytes are data there.
+func columnEmpty(col db.ColumnSchema, val any) bool {
+	if b, ok := val.([]byte); ok && logicalType(col) == db.TypeBinary {
+		return len(b) == 0
+	}
+	return isEmpty(val)
+}
+
+// maxLength returns the length fake strings are generated for in col
+func maxLength(col db.ColumnSchema) int {
+	if col.MaxLength == 0 {
+		return 255
+	}
+	return col.MaxLength
+}
+
+// transform returns the replacement for val under rule
+func transform(cfg *config.Config, state *rowState, data map[string]any, col db.ColumnSchema, rule config.ColumnRule, val any) (any, error) {
+	kind := rule.Strategy
+	if kind == "" {
+		kind = guessKind(col)
+	}
+	in := &Input{
+		Config: cfg, Value: val, Row: data, Column: col, Rule: rule, MaxLen: maxLength(col),
+		state: state, locale: localeFor(cfg, state, rule),
+	}
+	t, ok := lookupTransformer(kind)
+	if !ok {
+		return nil, fmt.Errorf("unknown anonymization strategy %q", kind)
+	}
+	seed := val
+	if columnEmpty(col, val) {
+		seed = emptySeed(state, col)
+	}
+	f := fakerFor(cfg, kind, seed)
+	if state != nil && !secretStrategies[kind] {
+		f = rowFaker(cfg, state, kind, state.draw(col.Name), seed)
+	}
+	fakeVal, err := t.Transform(f, in)
+	if err != nil {
+		return nil, err
+	}
+	// Truncate if needed; columns without a maximum length take values of any size.
+	// Lengths are in characters, so multibyte text is cut between runes.
+	if v, ok := fakeVal.(string); ok && col.MaxLength > 0 && utf8.RuneCountInString(v) > col.MaxLength {
+		fakeVal = string([]rune(v)[:col.MaxLength])
+	}
+	return fakeVal, nil
+}
+
 // Anonymize performs data anonymization on a row
-func Anonymize(row *Row, cfg *config.Config) {
+func Anonymize(row *Row, cfg *config.Config) error {
 	table := row.Schema.Name
 	fields, ok := cfg.AnonymizeFields[table]
 	if !ok {
-		return
+		return nil
 	}
 	fieldSet := make(map[string]struct{}, len(fields))
 	for _, f := range fields {
 		fieldSet[f] = struct{}{}
 	}
 
-	for _, col := range row.Schema.Columns {
+	// Keep the original values so rules can refer to other columns of the row
+	// regardless of whether those have already been replaced
+	state := &rowState{schema: row.Schema, original: make(map[string]any, len(row.Data))}
+	for k, v := range row.Data {
+		state.original[k] = v
+	}
+
+	columns, err := columnOrder(row.Schema, cfg)
+	if err != nil {
+		return err
+	}
+	for _, col := range columns {
 		if _, shouldAnon := fieldSet[col.Name]; !shouldAnon {
 			continue
 		}
 		val := row.Data[col.Name]
-		// Only anonymize non-nil values
-		if val == nil {
-			continue
-		}
-		// Skip if blank string
-		if s, ok := val.(string); ok && strings.TrimSpace(s) == "" {
+		rule := cfg.Rule(table, col.Name)
+		if !appliesTo(rule, state.original) {
+			if col.Unique {
+				ReserveUnique(table, col.Name, val)
+			}
 			continue
 		}
-		// Skip if numerically zero
-		switch v := val.(type) {
-		case int, int8, int16, int32, int64:
-			if toInt64(v) == 0 {
-				continue
-			}
-		case uint, uint8, uint16, uint32, uint64:
-			if toUint64(v) == 0 {
-				continue
-			}
-		case float32:
-			if v == 0.0 {
-				continue
-			}
-		case float64:
-			if v == 0.0 {
+		// Empty values are left alone unless the strategy blanks every value
+		// or the column's policy replaces them, with a fresh value
+		if !fixedStrategies[rule.Strategy] && columnEmpty(col, val) {
+			if !replacesEmpty(cfg, state, col, rule, val) {
+				if col.Unique {
+					ReserveUnique(table, col.Name, val)
+				}
 				continue
 			}
+			rule = emptyRule(col, rule)
 		}
 
-		var fakeVal any
-		lowerName := strings.ToLower(col.Name)
-		maxLen := col.MaxLength
-		if maxLen == 0 {
-			maxLen = 255
-		}
-		colType := strings.ToLower(col.Type)
-		switch {
-		case strings.Contains(colType, "int"):
-			fakeVal = gofakeit.Int64()
-		case strings.Contains(colType, "float") || strings.Contains(colType, "double") || strings.Contains(colType, "real") || strings.Contains(colType, "numeric") || strings.Contains(colType, "decimal"):
-			fakeVal = gofakeit.Float64()
-		case strings.Contains(lowerName, "email"):
-			fakeVal = gofakeit.Email()
-		case strings.Contains(lowerName, "phone"):
-			fakeVal = gofakeit.Phone()
-		case strings.Contains(lowerName, "name"):
-			fakeVal = gofakeit.Name()
-		default:
-			if maxLen >= 50 {
-				fakeVal = gofakeit.Sentence(5)
-			} else {
-				fakeVal = gofakeit.LetterN(uint(maxLen))
-			}
+		fakeVal, err := transform(cfg, state, row.Data, col, rule, val)
+		if err != nil {
+			return fmt.Errorf("column %s: %w", col.Name, err)
 		}
-		// Truncate if needed
-		switch v := fakeVal.(type) {
-		case string:
-			if len(v) > maxLen {
-				fakeVal = v[:maxLen]
+		if col.Unique && fakeVal != nil {
+			if fakeVal, err = claimUnique(table, col.Name, fakeVal, maxLength(col)); err != nil {
+				return fmt.Errorf("column %s: %w", col.Name, err)
 			}
 		}
 		row.Data[col.Name] = fakeVal
 	}
+	return nil
 }
@@ -1,7 +1,9 @@
 package anonymizer
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/andys/new_names/config"
 	"github.com/andys/new_names/db"
@@ -88,3 +90,424 @@ func TestAnonymize_HandlesEmptyStrings(t *testing.T) {
 
 	c.Assert(row.Data["email"], quicktest.Equals, "")
 }
+
+func TestAnonymize_KeyedIsDeterministicAcrossTables(t *testing.T) {
+	c := quicktest.New(t)
+	users := &db.TableSchema{
+		Name:    "users",
+		Columns: []db.ColumnSchema{{Name: "email", Type: "varchar", MaxLength: 100}},
+	}
+	orders := &db.TableSchema{
+		Name:    "orders",
+		Columns: []db.ColumnSchema{{Name: "customer_email", Type: "varchar", MaxLength: 100}},
+	}
+	cfg := &config.Config{
+		Key: "s3cret",
+		AnonymizeFields: map[string][]string{
+			"users":  {"email"},
+			"orders": {"customer_email"},
+		},
+	}
+
+	userRow := &Row{Schema: users, Data: map[string]interface{}{"email": "alice@corp.com"}}
+	orderRow := &Row{Schema: orders, Data: map[string]interface{}{"customer_email": []byte("alice@corp.com")}}
+	Anonymize(userRow, cfg)
+	Anonymize(orderRow, cfg)
+
+	c.Assert(userRow.Data["email"], quicktest.Not(quicktest.Equals), "alice@corp.com")
+	c.Assert(orderRow.Data["customer_email"], quicktest.Equals, userRow.Data["email"])
+
+	otherKey := &config.Config{Key: "different", AnonymizeFields: cfg.AnonymizeFields}
+	again := &Row{Schema: users, Data: map[string]interface{}{"email": "alice@corp.com"}}
+	Anonymize(again, otherKey)
+	c.Assert(again.Data["email"], quicktest.Not(quicktest.Equals), userRow.Data["email"])
+}
+
+func TestAnonymize_SeededRunsRepeat(t *testing.T) {
+	c := quicktest.New(t)
+	schema := &db.TableSchema{
+		Name:  "users",
+		HasID: true,
+		IDCol: "id",
+		Columns: []db.ColumnSchema{
+			{Name: "id", Type: "int", IsID: true},
+			{Name: "email", Type: "varchar", MaxLength: 100},
+			{Name: "visits", Type: "int"},
+			{Name: "scores", Type: "ARRAY", ElementType: "int4"},
+		},
+	}
+	run := func(seed uint64, email string) []map[string]any {
+		cfg := &config.Config{Seed: &seed, AnonymizeFields: map[string][]string{"users": {"email", "visits", "scores"}}}
+		var rows []map[string]any
+		for id := 1; id <= 3; id++ {
+			row := &Row{Schema: schema, Data: map[string]any{"id": id, "email": email, "visits": int64(id), "scores": "{1,2,3}"}}
+			c.Assert(Anonymize(row, cfg), quicktest.IsNil)
+			rows = append(rows, row.Data)
+		}
+		return rows
+	}
+
+	first := run(42, "alice@corp.com")
+	c.Assert(run(42, "alice@corp.com"), quicktest.DeepEquals, first)
+	c.Assert(run(43, "alice@corp.com"), quicktest.Not(quicktest.DeepEquals), first)
+	// Without a key, equal values in different rows still get different fakes
+	c.Assert(first[1]["email"], quicktest.Not(quicktest.Equals), first[0]["email"])
+	// The printed seed must not tie fakes to originals, so they depend only
+	// on where the value is
+	c.Assert(run(42, "bob@corp.com")[0]["email"], quicktest.Equals, first[0]["email"])
+	elems := strings.Split(strings.Trim(first[0]["scores"].(string), "{}"), ",")
+	c.Assert(elems[0] == elems[1] && elems[1] == elems[2], quicktest.IsFalse)
+}
+
+func TestAnonymize_SeedNeverDrivesSecrets(t *testing.T) {
+	c := quicktest.New(t)
+	seed := uint64(42)
+	cfg := &config.Config{Seed: &seed}
+	c.Assert(secretKey(cfg), quicktest.DeepEquals, secretKey(&config.Config{}))
+
+	schema := &db.TableSchema{Name: "users", HasID: true, IDCol: "id", Columns: []db.ColumnSchema{
+		{Name: "id", Type: "int", IsID: true}, {Name: "born", Type: "date"},
+	}}
+	cfg.AnonymizeFields = map[string][]string{"users": {"born"}}
+	born := time.Date(1980, 5, 1, 0, 0, 0, 0, time.UTC)
+	shifts := map[any]bool{}
+	for i := 0; i < 10; i++ {
+		row := &Row{Schema: schema, Data: map[string]any{"id": 1, "born": born}}
+		c.Assert(Anonymize(row, cfg), quicktest.IsNil)
+		shifts[row.Data["born"]] = true
+	}
+	c.Assert(len(shifts) > 1, quicktest.IsTrue)
+}
+
+func TestAnonymize_UsesExplicitStrategy(t *testing.T) {
+	c := quicktest.New(t)
+	schema := &db.TableSchema{
+		Name: "accounts",
+		Columns: []db.ColumnSchema{
+			{Name: "contact", Type: "varchar", MaxLength: 100},
+			{Name: "username", Type: "varchar", MaxLength: 100},
+		},
+	}
+	row := &Row{
+		Schema: schema,
+		Data: map[string]interface{}{
+			"contact":  "real@email.com",
+			"username": "realuser",
+		},
+	}
+	cfg := &config.Config{
+		AnonymizeFields: map[string][]string{"accounts": {"contact", "username"}},
+		ColumnRules: map[string]map[string]config.ColumnRule{
+			"accounts": {
+				"contact":  {Strategy: "email"},
+				"username": {Strategy: "uuid"},
+			},
+		},
+	}
+
+	Anonymize(row, cfg)
+
+	c.Assert(row.Data["contact"], quicktest.Matches, `\S+@\S+\.\S+`)
+	c.Assert(row.Data["username"], quicktest.Matches, `[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+}
+
+func TestValidate_RejectsUnknownStrategy(t *testing.T) {
+	c := quicktest.New(t)
+	schemas := []db.TableSchema{{
+		Name:    "accounts",
+		Columns: []db.ColumnSchema{{Name: "contact", Type: "varchar"}},
+	}}
+	cfg := &config.Config{
+		ColumnRules: map[string]map[string]config.ColumnRule{
+			"accounts": {"contact": {Strategy: "emial"}},
+		},
+	}
+
+	err := Validate(cfg, schemas)
+	c.Assert(err, quicktest.ErrorMatches, `column accounts.contact: unknown anonymization strategy "emial"`)
+
+	cfg.ColumnRules["accounts"]["contact"] = config.ColumnRule{Strategy: "email"}
+	c.Assert(Validate(cfg, schemas), quicktest.IsNil)
+}
+
+func TestAnonymize_MaskKeepsIntegerType(t *testing.T) {
+	c := quicktest.New(t)
+	schema := &db.TableSchema{
+		Name:    "accounts",
+		Columns: []db.ColumnSchema{{Name: "account_no", Type: "bigint"}},
+	}
+	row := &Row{Schema: schema, Data: map[string]interface{}{"account_no": int64(12345678)}}
+	cfg := &config.Config{
+		AnonymizeFields: map[string][]string{"accounts": {"account_no"}},
+		ColumnRules: map[string]map[string]config.ColumnRule{
+			"accounts": {"account_no": {Strategy: "mask", KeepPrefix: 2}},
+		},
+	}
+
+	Anonymize(row, cfg)
+
+	n, ok := row.Data["account_no"].(int64)
+	c.Assert(ok, quicktest.IsTrue)
+	c.Assert(n >= 12000000 && n < 13000000, quicktest.IsTrue)
+}
+
+func TestAnonymize_BlankingStrategies(t *testing.T) {
+	c := quicktest.New(t)
+	schema := &db.TableSchema{
+		Name: "users",
+		Columns: []db.ColumnSchema{
+			{Name: "notes", Type: "text", Nullable: true},
+			{Name: "ssn", Type: "varchar", MaxLength: 11},
+			{Name: "api_token", Type: "varchar", MaxLength: 8},
+			{Name: "country", Type: "varchar", MaxLength: 2},
+		},
+	}
+	row := &Row{
+		Schema: schema,
+		Data: map[string]interface{}{
+			"notes":     "called about their divorce",
+			"ssn":       "",
+			"api_token": "sk_live_abcdef",
+			"country":   nil,
+		},
+	}
+	xx := "XX"
+	cfg := &config.Config{
+		AnonymizeFields: map[string][]string{"users": {"notes", "ssn", "api_token", "country"}},
+		ColumnRules: map[string]map[string]config.ColumnRule{
+			"users": {
+				"notes":     {Strategy: "null"},
+				"ssn":       {Strategy: "redact"},
+				"api_token": {Strategy: "redact"},
+				"country":   {Strategy: "constant", Value: &xx},
+			},
+		},
+	}
+
+	Anonymize(row, cfg)
+
+	c.Assert(row.Data["notes"], quicktest.IsNil)
+	c.Assert(row.Data["ssn"], quicktest.Equals, "[REDACTED]")
+	c.Assert(row.Data["api_token"], quicktest.Equals, "[REDACTE")
+	c.Assert(row.Data["country"], quicktest.Equals, "XX")
+}
+
+func TestValidate_NullStrategyOnNotNullColumn(t *testing.T) {
+	c := quicktest.New(t)
+	schemas := []db.TableSchema{{
+		Name:    "users",
+		Columns: []db.ColumnSchema{{Name: "ssn", Type: "varchar", Nullable: false}},
+	}}
+	cfg := &config.Config{
+		ColumnRules: map[string]map[string]config.ColumnRule{
+			"users": {"ssn": {Strategy: "null"}},
+		},
+	}
+
+	err := Validate(cfg, schemas)
+	c.Assert(err, quicktest.ErrorMatches, "column users.ssn is NOT NULL and cannot use the null strategy")
+
+	cfg.ColumnRules["users"]["ssn"] = config.ColumnRule{Strategy: "constant"}
+	err = Validate(cfg, schemas)
+	c.Assert(err, quicktest.ErrorMatches, "column users.ssn: constant strategy requires a value")
+
+	cfg.ColumnRules["users"]["ssn"] = config.ColumnRule{Strategy: "mask", KeepSuffix: -2}
+	err = Validate(cfg, schemas)
+	c.Assert(err, quicktest.ErrorMatches, "column users.ssn: keep_prefix and keep_suffix must not be negative")
+}
+
+func TestAnonymize_HashTruncatesToColumnLength(t *testing.T) {
+	c := quicktest.New(t)
+	users := &db.TableSchema{
+		Name:    "users",
+		Columns: []db.ColumnSchema{{Name: "external_customer_ref", Type: "varchar", MaxLength: 16}},
+	}
+	exports := &db.TableSchema{
+		Name:    "exports",
+		Columns: []db.ColumnSchema{{Name: "customer_ref", Type: "varchar", MaxLength: 16}},
+	}
+	rule := config.ColumnRule{Strategy: "hash", Salt: "pepper"}
+	cfg := &config.Config{
+		AnonymizeFields: map[string][]string{
+			"users":   {"external_customer_ref"},
+			"exports": {"customer_ref"},
+		},
+		ColumnRules: map[string]map[string]config.ColumnRule{
+			"users":   {"external_customer_ref": rule},
+			"exports": {"customer_ref": rule},
+		},
+	}
+
+	userRow := &Row{Schema: users, Data: map[string]interface{}{"external_customer_ref": "CUST-0001"}}
+	exportRow := &Row{Schema: exports, Data: map[string]interface{}{"customer_ref": []byte("CUST-0001")}}
+	Anonymize(userRow, cfg)
+	Anonymize(exportRow, cfg)
+
+	c.Assert(userRow.Data["external_customer_ref"], quicktest.Matches, `[0-9a-f]{16}`)
+	c.Assert(exportRow.Data["customer_ref"], quicktest.Equals, userRow.Data["external_customer_ref"])
+}
+
+func TestValidate_HashRequiresSalt(t *testing.T) {
+	c := quicktest.New(t)
+	schemas := []db.TableSchema{{
+		Name:    "users",
+		Columns: []db.ColumnSchema{{Name: "ref", Type: "varchar"}},
+	}}
+	cfg := &config.Config{
+		ColumnRules: map[string]map[string]config.ColumnRule{
+			"users": {"ref": {Strategy: "hash"}},
+		},
+	}
+
+	c.Assert(Validate(cfg, schemas), quicktest.ErrorMatches, "column users.ref: hash strategy requires a salt or a key")
+	cfg.Key = "s3cret"
+	c.Assert(Validate(cfg, schemas), quicktest.IsNil)
+	cfg.ColumnRules["users"]["ref"] = config.ColumnRule{Strategy: "hash", Encoding: "base64"}
+	c.Assert(Validate(cfg, schemas), quicktest.ErrorMatches, `column users.ref: unknown hash encoding "base64"`)
+
+	cfg.ColumnRules["users"]["ref"] = config.ColumnRule{Strategy: "hash"}
+	schemas[0].Columns[0].Type = "bigint"
+	c.Assert(Validate(cfg, schemas), quicktest.ErrorMatches, `column users.ref: hash strategy needs a text column, not bigint`)
+}
+
+func TestAnonymize_DateShiftPreservesIntervalsPerEntity(t *testing.T) {
+	c := quicktest.New(t)
+	schema := &db.TableSchema{
+		Name: "appointments",
+		Columns: []db.ColumnSchema{
+			{Name: "user_id", Type: "int"},
+			{Name: "starts_at", Type: "datetime"},
+		},
+	}
+	cfg := &config.Config{
+		AnonymizeFields: map[string][]string{"appointments": {"starts_at"}},
+		ColumnRules: map[string]map[string]config.ColumnRule{
+			"appointments": {"starts_at": {Strategy: "date_shift", Entity: "user_id", MaxDays: 90}},
+		},
+	}
+
+	first := &Row{Schema: schema, Data: map[string]interface{}{"user_id": int64(7), "starts_at": "2024-05-01 09:00:00"}}
+	second := &Row{Schema: schema, Data: map[string]interface{}{"user_id": int64(7), "starts_at": "2024-05-15 09:00:00"}}
+	c.Assert(Anonymize(first, cfg), quicktest.IsNil)
+	c.Assert(Anonymize(second, cfg), quicktest.IsNil)
+
+	a, err := time.Parse("2006-01-02 15:04:05", first.Data["starts_at"].(string))
+	c.Assert(err, quicktest.IsNil)
+	b, err := time.Parse("2006-01-02 15:04:05", second.Data["starts_at"].(string))
+	c.Assert(err, quicktest.IsNil)
+	c.Assert(a.Format("2006-01-02"), quicktest.Not(quicktest.Equals), "2024-05-01")
+	c.Assert(b.Sub(a), quicktest.Equals, 14*24*time.Hour)
+}
+
+func TestAnonymize_GuessesDateShiftForDateColumns(t *testing.T) {
+	c := quicktest.New(t)
+	schema := &db.TableSchema{
+		Name:    "users",
+		Columns: []db.ColumnSchema{{Name: "birthdate", Type: "date"}},
+	}
+	row := &Row{Schema: schema, Data: map[string]interface{}{"birthdate": []byte("1980-06-15")}}
+	cfg := &config.Config{AnonymizeFields: map[string][]string{"users": {"birthdate"}}}
+
+	c.Assert(Anonymize(row, cfg), quicktest.IsNil)
+	c.Assert(row.Data["birthdate"], quicktest.Matches, `\d{4}-\d{2}-\d{2}`)
+	c.Assert(row.Data["birthdate"], quicktest.Not(quicktest.Equals), "1980-06-15")
+}
+
+func TestAnonymize_TemplateUsesFakeValues(t *testing.T) {
+	c := quicktest.New(t)
+	schema := &db.TableSchema{
+		Name: "users",
+		Columns: []db.ColumnSchema{
+			{Name: "email", Type: "varchar", MaxLength: 100},
+			{Name: "first_name", Type: "varchar", MaxLength: 50},
+			{Name: "last_name", Type: "varchar", MaxLength: 50},
+		},
+	}
+	row := &Row{
+		Schema: schema,
+		Data: map[string]interface{}{
+			"email":      "jane.doe@corp.com",
+			"first_name": "Jane",
+			"last_name":  "Doe",
+		},
+	}
+	cfg := &config.Config{
+		AnonymizeFields: map[string][]string{"users": {"email", "first_name", "last_name"}},
+		ColumnRules: map[string]map[string]config.ColumnRule{
+			"users": {
+				"email":      {Strategy: "template", Template: "{{first_name | slug}}.{{last_name | slug}}@example.test"},
+				"first_name": {Strategy: "first_name"},
+				"last_name":  {Strategy: "last_name"},
+			},
+		},
+	}
+
+	c.Assert(Anonymize(row, cfg), quicktest.IsNil)
+
+	c.Assert(row.Data["first_name"], quicktest.Not(quicktest.Equals), "Jane")
+	want := RenderTemplate("{{first_name | slug}}.{{last_name | slug}}@example.test", row.Data)
+	c.Assert(row.Data["email"], quicktest.Equals, want)
+}
+
+func TestAnonymize_PersonFieldsShareOneIdentity(t *testing.T) {
+	c := quicktest.New(t)
+	schema := &db.TableSchema{
+		Name: "users",
+		Columns: []db.ColumnSchema{
+			{Name: "given_name", Type: "varchar", MaxLength: 50},
+			{Name: "family_name", Type: "varchar", MaxLength: 50},
+			{Name: "login", Type: "varchar", MaxLength: 100},
+		},
+	}
+	newRow := func() *Row {
+		return &Row{Schema: schema, Data: map[string]interface{}{
+			"given_name":  "Maria",
+			"family_name": "Lopez",
+			"login":       "jdoe1977@corp.com",
+		}}
+	}
+	cfg := &config.Config{
+		Key:             "s3cret",
+		AnonymizeFields: map[string][]string{"users": {"given_name", "family_name", "login"}},
+		ColumnRules: map[string]map[string]config.ColumnRule{
+			"users": {
+				"given_name":  {Strategy: "person", Field: "first_name"},
+				"family_name": {Strategy: "person", Field: "last_name"},
+				"login":       {Strategy: "person", Field: "email"},
+			},
+		},
+	}
+
+	row := newRow()
+	c.Assert(Anonymize(row, cfg), quicktest.IsNil)
+	prefix := strings.ToLower(row.Data["given_name"].(string) + "." + row.Data["family_name"].(string))
+	c.Assert(strings.HasPrefix(row.Data["login"].(string), prefix), quicktest.IsTrue)
+
+	// Keyed mode gives the same person for the same original row
+	again := newRow()
+	c.Assert(Anonymize(again, cfg), quicktest.IsNil)
+	c.Assert(again.Data, quicktest.DeepEquals, row.Data)
+}
+
+func TestAnonymize_UniqueColumnsNeverRepeat(t *testing.T) {
+	c := quicktest.New(t)
+	schema := &db.TableSchema{
+		Name:    "unique_accounts",
+		Columns: []db.ColumnSchema{{Name: "plan", Type: "varchar", MaxLength: 20, Unique: true}},
+	}
+	free := "free"
+	cfg := &config.Config{
+		AnonymizeFields: map[string][]string{"unique_accounts": {"plan"}},
+		ColumnRules: map[string]map[string]config.ColumnRule{
+			"unique_accounts": {"plan": {Strategy: "constant", Value: &free}},
+		},
+	}
+
+	seen := make(map[any]bool)
+	for i := 0; i < 50; i++ {
+		row := &Row{Schema: schema, Data: map[string]interface{}{"plan": "gold"}}
+		c.Assert(Anonymize(row, cfg), quicktest.IsNil)
+		c.Assert(seen[row.Data["plan"]], quicktest.IsFalse)
+		seen[row.Data["plan"]] = true
+	}
+}
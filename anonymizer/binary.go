@@ -0,0 +1,131 @@
+package anonymizer
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"strings"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// maxPlaceholderSide caps the width and height of placeholder images, which
+// otherwise take the original's size
+const maxPlaceholderSide = 4096
+
+// defaultPlaceholderSide is the size of placeholder images whose original's
+// size can't be read
+const defaultPlaceholderSide = 64
+
+// bytesValue returns a scanned value as bytes
+func bytesValue(v any) []byte {
+	if b, ok := v.([]byte); ok {
+		return b
+	}
+	return []byte(stringValue(v))
+}
+
+// DetectMIME returns the MIME type of binary content from its leading magic
+// bytes, e.g. image/png or application/pdf, or application/octet-stream
+// when it isn't recognized
+func DetectMIME(data []byte) string {
+	mime, _, _ := strings.Cut(http.DetectContentType(data), ";")
+	return mime
+}
+
+// placeholderImage returns a flat-coloured image the size of the original
+// image, or of the default size when that can't be read
+func placeholderImage(f *gofakeit.Faker, original []byte) image.Image {
+	width, height := defaultPlaceholderSide, defaultPlaceholderSide
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(original)); err == nil && cfg.Width > 0 && cfg.Height > 0 {
+		width, height = min(cfg.Width, maxPlaceholderSide), min(cfg.Height, maxPlaceholderSide)
+	}
+	grey := uint8(f.IntRange(160, 230))
+	fill := color.RGBA{R: grey, G: grey, B: grey, A: 0xff}
+	return image.NewPaletted(image.Rect(0, 0, width, height), color.Palette{fill})
+}
+
+// placeholderPDF returns a one-page PDF saying it stands in for a document
+func placeholderPDF() []byte {
+	const content = "BT /F1 24 Tf 72 720 Td (Placeholder document) Tj ET"
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes()
+}
+
+// Placeholder returns generated content of the same type as original: a
+// flat grey image of the same size for PNG, JPEG and GIF images, or a
+// one-page PDF. Content of other types is zero-filled.
+func Placeholder(f *gofakeit.Faker, original []byte) ([]byte, error) {
+	var b bytes.Buffer
+	var err error
+	switch DetectMIME(original) {
+	case "image/png":
+		err = png.Encode(&b, placeholderImage(f, original))
+	case "image/jpeg":
+		err = jpeg.Encode(&b, placeholderImage(f, original), nil)
+	case "image/gif":
+		err = gif.Encode(&b, placeholderImage(f, original), nil)
+	case "application/pdf":
+		return placeholderPDF(), nil
+	default:
+		return make([]byte, len(original)), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// placeholderValue replaces binary content with a placeholder of the same
+// type. If the placeholder won't fit in the column, the original is
+// zero-filled instead.
+func placeholderValue(f *gofakeit.Faker, in *Input) (any, error) {
+	original := bytesValue(in.Value)
+	out, err := Placeholder(f, original)
+	if err != nil {
+		return nil, err
+	}
+	if in.Column.MaxLength > 0 && len(out) > in.Column.MaxLength {
+		return make([]byte, len(original)), nil
+	}
+	return out, nil
+}
+
+// zeroFillValue replaces binary content with as many zero bytes
+func zeroFillValue(_ *gofakeit.Faker, in *Input) (any, error) {
+	return make([]byte, len(bytesValue(in.Value))), nil
+}
+
+// truncateValue keeps the first rule.Bytes bytes of binary content
+func truncateValue(_ *gofakeit.Faker, in *Input) (any, error) {
+	b := bytesValue(in.Value)
+	if len(b) > in.Rule.Bytes {
+		b = b[:in.Rule.Bytes]
+	}
+	return append([]byte(nil), b...), nil
+}
@@ -0,0 +1,75 @@
+package anonymizer
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/andys/new_names/config"
+	"github.com/andys/new_names/db"
+	"github.com/frankban/quicktest"
+)
+
+var documentsSchema = &db.TableSchema{
+	Name:  "documents",
+	HasID: true,
+	IDCol: "id",
+	Columns: []db.ColumnSchema{
+		{Name: "id", Type: "int", IsID: true},
+		{Name: "file_data", Type: "longblob", Nullable: true},
+	},
+}
+
+func anonymizeDocument(c *quicktest.C, rule config.ColumnRule, data []byte) any {
+	cfg := &config.Config{
+		AnonymizeFields: map[string][]string{"documents": {"file_data"}},
+		ColumnRules:     map[string]map[string]config.ColumnRule{"documents": {"file_data": rule}},
+	}
+	row := &Row{Schema: documentsSchema, Data: map[string]any{"id": 1, "file_data": data}}
+	c.Assert(Anonymize(row, cfg), quicktest.IsNil)
+	return row.Data["file_data"]
+}
+
+func testPNG(c *quicktest.C, width, height int) []byte {
+	var b bytes.Buffer
+	c.Assert(png.Encode(&b, image.NewGray(image.Rect(0, 0, width, height))), quicktest.IsNil)
+	return b.Bytes()
+}
+
+func TestDetectMIME(t *testing.T) {
+	c := quicktest.New(t)
+	c.Assert(DetectMIME(testPNG(c, 1, 1)), quicktest.Equals, "image/png")
+	c.Assert(DetectMIME([]byte("%PDF-1.7\n")), quicktest.Equals, "application/pdf")
+	c.Assert(DetectMIME([]byte{0xff, 0xd8, 0xff, 0xe0}), quicktest.Equals, "image/jpeg")
+	c.Assert(DetectMIME([]byte{0x00, 0x01, 0x02}), quicktest.Equals, "application/octet-stream")
+}
+
+func TestPlaceholder_KeepsTypeAndSize(t *testing.T) {
+	c := quicktest.New(t)
+	original := testPNG(c, 30, 20)
+	v := anonymizeDocument(c, config.ColumnRule{Strategy: "placeholder"}, original).([]byte)
+	c.Assert(v, quicktest.Not(quicktest.DeepEquals), original)
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(v))
+	c.Assert(err, quicktest.IsNil)
+	c.Assert(format, quicktest.Equals, "png")
+	c.Assert([]int{cfg.Width, cfg.Height}, quicktest.DeepEquals, []int{30, 20})
+
+	pdf := anonymizeDocument(c, config.ColumnRule{}, []byte("%PDF-1.4\nsecret contract terms")).([]byte)
+	c.Assert(DetectMIME(pdf), quicktest.Equals, "application/pdf")
+	c.Assert(bytes.Contains(pdf, []byte("secret")), quicktest.IsFalse)
+
+	other := anonymizeDocument(c, config.ColumnRule{Strategy: "placeholder"}, []byte{0x00, 0x01, 0x02})
+	c.Assert(other, quicktest.DeepEquals, []byte{0, 0, 0})
+}
+
+func TestZeroFillAndTruncate(t *testing.T) {
+	c := quicktest.New(t)
+	c.Assert(anonymizeDocument(c, config.ColumnRule{Strategy: "zero_fill"}, []byte("scan")), quicktest.DeepEquals, []byte{0, 0, 0, 0})
+	c.Assert(anonymizeDocument(c, config.ColumnRule{Strategy: "truncate", Bytes: 2}, []byte("scan")), quicktest.DeepEquals, []byte("sc"))
+	c.Assert(anonymizeDocument(c, config.ColumnRule{Strategy: "truncate", Bytes: 8}, []byte("scan")), quicktest.DeepEquals, []byte("scan"))
+
+	cfg := &config.Config{ColumnRules: map[string]map[string]config.ColumnRule{"documents": {"file_data": {Strategy: "truncate"}}}}
+	c.Assert(Validate(cfg, []db.TableSchema{*documentsSchema}), quicktest.ErrorMatches,
+		`column documents.file_data: truncate strategy requires a positive bytes length`)
+}
@@ -0,0 +1,116 @@
+package anonymizer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/andys/new_names/config"
+	"github.com/andys/new_names/db"
+)
+
+// boolValue interprets a scanned value as a boolean the way databases
+// commonly store one: bool, 1/0, t/f, true/false or yes/no
+func boolValue(val any) (bool, bool) {
+	switch v := val.(type) {
+	case bool:
+		return v, true
+	case int, int8, int16, int32, int64:
+		return toInt64(v) != 0, true
+	case uint, uint8, uint16, uint32, uint64:
+		return toUint64(v) != 0, true
+	case nil:
+		return false, false
+	}
+	switch strings.ToLower(strings.TrimSpace(stringValue(val))) {
+	case "1", "t", "true", "y", "yes":
+		return true, true
+	case "0", "f", "false", "n", "no":
+		return false, true
+	}
+	return false, false
+}
+
+// valueEquals reports whether a column value equals a value written in config
+func valueEquals(val any, want string) bool {
+	if val == nil {
+		return false
+	}
+	if stringValue(val) == want {
+		return true
+	}
+	wantBool, err := strconv.ParseBool(want)
+	if err != nil {
+		return false
+	}
+	got, ok := boolValue(val)
+	return ok && got == wantBool
+}
+
+// conditionHolds evaluates one condition against a row
+func conditionHolds(cond config.Condition, row map[string]any) bool {
+	val := row[cond.Column]
+	if cond.IsNull != nil && (val == nil) != *cond.IsNull {
+		return false
+	}
+	if cond.Equals != nil && !valueEquals(val, *cond.Equals) {
+		return false
+	}
+	if cond.In != nil {
+		found := false
+		for _, want := range cond.In {
+			found = found || valueEquals(val, want)
+		}
+		if !found {
+			return false
+		}
+	}
+	if cond.Matches != "" {
+		re, err := compileCached(cond.Matches)
+		if err != nil || val == nil || !re.MatchString(stringValue(val)) {
+			return false
+		}
+	}
+	return true
+}
+
+// conditionsHold reports whether every condition holds for the row
+func conditionsHold(conds config.Conditions, row map[string]any) bool {
+	for _, cond := range conds {
+		if !conditionHolds(cond, row) {
+			return false
+		}
+	}
+	return true
+}
+
+// appliesTo reports whether a rule's when and unless conditions let it
+// anonymize a row, judged by the row's original values
+func appliesTo(rule config.ColumnRule, original map[string]any) bool {
+	if len(rule.When) > 0 && !conditionsHold(rule.When, original) {
+		return false
+	}
+	if len(rule.Unless) > 0 && conditionsHold(rule.Unless, original) {
+		return false
+	}
+	return true
+}
+
+// validateConditions checks that conditions name existing columns and
+// test something valid
+func validateConditions(schema db.TableSchema, conds config.Conditions) error {
+	for _, cond := range conds {
+		if !hasColumn(schema, cond.Column) {
+			return fmt.Errorf("condition column %q does not exist", cond.Column)
+		}
+		if cond.Equals == nil && cond.In == nil && cond.Matches == "" && cond.IsNull == nil {
+			return fmt.Errorf("condition on %s needs equals, in, matches or is_null", cond.Column)
+		}
+		if cond.Matches != "" {
+			if _, err := compileCached(cond.Matches); err != nil {
+				return fmt.Errorf("condition on %s: %w", cond.Column, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package anonymizer
+
+import (
+	"testing"
+
+	"github.com/andys/new_names/config"
+	"github.com/andys/new_names/db"
+	"github.com/frankban/quicktest"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func TestConditionHolds(t *testing.T) {
+	c := quicktest.New(t)
+	row := map[string]any{
+		"email":       "ops@ourcompany.com",
+		"is_business": int64(0),
+		"status":      []byte("active"),
+		"deleted_at":  nil,
+	}
+
+	c.Assert(conditionHolds(config.Condition{Column: "email", Matches: `@ourcompany\.com$`}, row), quicktest.IsTrue)
+	c.Assert(conditionHolds(config.Condition{Column: "is_business", Equals: ptr("false")}, row), quicktest.IsTrue)
+	c.Assert(conditionHolds(config.Condition{Column: "is_business", Equals: ptr("true")}, row), quicktest.IsFalse)
+	c.Assert(conditionHolds(config.Condition{Column: "status", In: []string{"pending", "active"}}, row), quicktest.IsTrue)
+	c.Assert(conditionHolds(config.Condition{Column: "status", In: []string{"closed"}}, row), quicktest.IsFalse)
+	c.Assert(conditionHolds(config.Condition{Column: "deleted_at", IsNull: ptr(true)}, row), quicktest.IsTrue)
+	c.Assert(conditionHolds(config.Condition{Column: "deleted_at", IsNull: ptr(false)}, row), quicktest.IsFalse)
+	c.Assert(conditionHolds(config.Condition{Column: "deleted_at", Matches: `.*`}, row), quicktest.IsFalse)
+	c.Assert(conditionHolds(config.Condition{Column: "status", Equals: ptr("active"), IsNull: ptr(true)}, row), quicktest.IsFalse)
+}
+
+func TestAnonymize_WhenAndUnless(t *testing.T) {
+	c := quicktest.New(t)
+	schema := &db.TableSchema{
+		Name: "users",
+		Columns: []db.ColumnSchema{
+			{Name: "email", Type: "varchar"},
+			{Name: "address", Type: "varchar"},
+			{Name: "is_business", Type: "boolean"},
+		},
+	}
+	cfg := &config.Config{
+		AnonymizeFields: map[string][]string{"users": {"email", "address"}},
+		ColumnRules: map[string]map[string]config.ColumnRule{"users": {
+			"email": {
+				Strategy: "email",
+				Unless:   config.Conditions{{Column: "email", Matches: `@ourcompany\.com$`}},
+			},
+			"address": {
+				Strategy: "redact",
+				When:     config.Conditions{{Column: "is_business", Equals: ptr("false")}},
+			},
+		}},
+	}
+
+	staff := &Row{Schema: schema, Data: map[string]interface{}{"email": "ops@ourcompany.com", "address": "1 Main St", "is_business": true}}
+	c.Assert(Anonymize(staff, cfg), quicktest.IsNil)
+	c.Assert(staff.Data["email"], quicktest.Equals, "ops@ourcompany.com")
+	c.Assert(staff.Data["address"], quicktest.Equals, "1 Main St")
+
+	customer := &Row{Schema: schema, Data: map[string]interface{}{"email": "jane@gmail.com", "address": "2 High St", "is_business": false}}
+	c.Assert(Anonymize(customer, cfg), quicktest.IsNil)
+	c.Assert(customer.Data["email"], quicktest.Not(quicktest.Equals), "jane@gmail.com")
+	c.Assert(customer.Data["address"], quicktest.Equals, redactedMarker)
+}
+
+func TestValidate_Conditions(t *testing.T) {
+	c := quicktest.New(t)
+	schemas := []db.TableSchema{{
+		Name:    "users",
+		Columns: []db.ColumnSchema{{Name: "email", Type: "varchar"}},
+	}}
+	validate := func(cond config.Condition) error {
+		cfg := &config.Config{ColumnRules: map[string]map[string]config.ColumnRule{
+			"users": {"email": {Unless: config.Conditions{cond}}},
+		}}
+		return Validate(cfg, schemas)
+	}
+
+	c.Assert(validate(config.Condition{Column: "mail", IsNull: ptr(true)}), quicktest.ErrorMatches, `column users.email: condition column "mail" does not exist`)
+	c.Assert(validate(config.Condition{Column: "email"}), quicktest.ErrorMatches, `column users.email: condition on email needs equals, in, matches or is_null`)
+	c.Assert(validate(config.Condition{Column: "email", Matches: `(`}), quicktest.ErrorMatches, `column users.email: condition on email: error parsing regexp.*`)
+	c.Assert(validate(config.Condition{Column: "email", Matches: `@ourcompany\.com$`}), quicktest.IsNil)
+}
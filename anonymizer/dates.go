@@ -0,0 +1,101 @@
+package anonymizer
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andys/new_names/config"
+)
+
+// defaultShiftDays is the date_shift range used when max_days isn't set
+const defaultShiftDays = 365
+
+var (
+	runSecretOnce sync.Once
+	runSecret     []byte
+)
+
+// secretKey returns the configured key, or a random key that lasts for this
+// run when none is configured. Offsets derived from it are then consistent
+// within a run, but can't be recomputed afterwards. It is never derived
+// from the run's seed, which is printed.
+func secretKey(cfg *config.Config) []byte {
+	if cfg.Key != "" {
+		return []byte(cfg.Key)
+	}
+	runSecretOnce.Do(func() {
+		runSecret = make([]byte, 32)
+		if _, err := rand.Read(runSecret); err != nil {
+			panic(fmt.Sprintf("failed to generate run secret: %v", err))
+		}
+	})
+	return runSecret
+}
+
+// nonZeroOffset maps n onto a whole number of days in [-maxDays, maxDays], never zero
+func nonZeroOffset(n uint64, maxDays int) int {
+	days := int(n%uint64(2*maxDays)) - maxDays
+	if days >= 0 {
+		days++
+	}
+	return days
+}
+
+// entityShiftDays returns the shift for every date belonging to entity, so
+// the intervals between one entity's events are preserved
+func entityShiftDays(secret []byte, entity string, maxDays int) int {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("date_shift"))
+	mac.Write([]byte{0})
+	mac.Write([]byte(entity))
+	return nonZeroOffset(binary.BigEndian.Uint64(mac.Sum(nil)), maxDays)
+}
+
+// ShiftDate moves a date or timestamp value by the given number of days.
+// time.Time values stay time.Time; textual values keep their layout.
+func ShiftDate(val any, days int) (any, error) {
+	if t, ok := val.(time.Time); ok {
+		return t.AddDate(0, 0, days), nil
+	}
+	s := stringValue(val)
+	// MySQL zero dates carry no information to shift
+	if strings.HasPrefix(s, "0000-00-00") {
+		return val, nil
+	}
+	layout := dateLayout(s)
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		return nil, badValue(fmt.Errorf("cannot parse %q as a date", s))
+	}
+	return t.AddDate(0, 0, days).Format(layout), nil
+}
+
+// dateLayout works out the layout of a textual date, such as "2006-01-02" or
+// "2006-01-02T15:04:05.000Z07:00", so a shifted value is written back in the
+// same form and precision
+func dateLayout(s string) string {
+	const datePart = "2006-01-02"
+	if len(s) <= len(datePart) {
+		return datePart
+	}
+	layout := datePart + s[10:11] + "15:04:05"
+	rest := s[min(len(s), len(layout)):]
+	if strings.HasPrefix(rest, ".") {
+		n := 1
+		for n < len(rest) && rest[n] >= '0' && rest[n] <= '9' {
+			n++
+		}
+		layout += "." + strings.Repeat("0", n-1)
+		rest = rest[n:]
+	}
+	if rest != "" {
+		layout += "Z07:00"
+	}
+	return layout
+}
@@ -0,0 +1,57 @@
+package anonymizer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/frankban/quicktest"
+)
+
+func TestShiftDate_KeepsTextLayout(t *testing.T) {
+	c := quicktest.New(t)
+
+	tests := []struct {
+		in   any
+		days int
+		want any
+	}{
+		{"2024-02-28", 2, "2024-03-01"},
+		{[]byte("2024-01-31 23:59:59"), -31, "2023-12-31 23:59:59"},
+		{"2024-01-01T08:30:00.123456", 1, "2024-01-02T08:30:00.123456"},
+		{"2024-01-01T08:30:00+02:00", 10, "2024-01-11T08:30:00+02:00"},
+		{"0000-00-00 00:00:00", 5, "0000-00-00 00:00:00"},
+	}
+	for _, test := range tests {
+		got, err := ShiftDate(test.in, test.days)
+		c.Assert(err, quicktest.IsNil)
+		c.Assert(got, quicktest.Equals, test.want)
+	}
+}
+
+func TestShiftDate_TimeValue(t *testing.T) {
+	c := quicktest.New(t)
+	in := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	got, err := ShiftDate(in, -1)
+	c.Assert(err, quicktest.IsNil)
+	c.Assert(got, quicktest.Equals, time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC))
+}
+
+func TestShiftDate_InvalidDate(t *testing.T) {
+	c := quicktest.New(t)
+	_, err := ShiftDate("next tuesday", 1)
+	c.Assert(err, quicktest.ErrorMatches, `cannot parse "next tuesday" as a date`)
+	c.Assert(err, quicktest.ErrorIs, ErrBadValue)
+}
+
+func TestEntityShiftDays_ConsistentAndInRange(t *testing.T) {
+	c := quicktest.New(t)
+	secret := []byte("s3cret")
+
+	for _, entity := range []string{"1", "2", "42", "user-7"} {
+		days := entityShiftDays(secret, entity, 30)
+		c.Assert(days, quicktest.Not(quicktest.Equals), 0)
+		c.Assert(days >= -30 && days <= 30, quicktest.IsTrue)
+		c.Assert(entityShiftDays(secret, entity, 30), quicktest.Equals, days)
+	}
+}
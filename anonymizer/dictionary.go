@@ -0,0 +1,162 @@
+package anonymizer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andys/new_names/config"
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// dictionary is a list of replacement values loaded from a file
+type dictionary struct {
+	values     []string
+	cumulative []float64         // Running total of weights, nil when unweighted
+	mapping    map[string]string // Original to replacement, in map mode
+}
+
+// pick returns a random value, honouring weights
+func (d *dictionary) pick(f *gofakeit.Faker) string {
+	if d.cumulative == nil {
+		return d.values[f.IntN(len(d.values))]
+	}
+	r := f.Float64() * d.cumulative[len(d.cumulative)-1]
+	i := sort.Search(len(d.cumulative), func(i int) bool { return d.cumulative[i] > r })
+	return d.values[min(i, len(d.values)-1)]
+}
+
+// dictionaries caches loaded files by path and options, as every row of a
+// table uses the same file
+var dictionaries sync.Map
+
+type dictionaryEntry struct {
+	dict *dictionary
+	err  error
+}
+
+// dictionaryPath resolves a dictionary file relative to the config file
+func dictionaryPath(cfg *config.Config, file string) string {
+	if filepath.IsAbs(file) || cfg.ConfigFile == "" {
+		return file
+	}
+	return filepath.Join(filepath.Dir(cfg.ConfigFile), file)
+}
+
+// loadDictionary reads the dictionary file of a rule, once per run
+func loadDictionary(cfg *config.Config, rule config.ColumnRule) (*dictionary, error) {
+	path := dictionaryPath(cfg, rule.File)
+	key := fmt.Sprintf("%s\x00%t\x00%t", path, rule.Map, rule.Header)
+	if entry, ok := dictionaries.Load(key); ok {
+		return entry.(dictionaryEntry).dict, entry.(dictionaryEntry).err
+	}
+	dict, err := readDictionary(path, rule)
+	if err != nil {
+		err = fmt.Errorf("dictionary %s: %w", rule.File, err)
+	}
+	entry, _ := dictionaries.LoadOrStore(key, dictionaryEntry{dict, err})
+	return entry.(dictionaryEntry).dict, entry.(dictionaryEntry).err
+}
+
+// readDictionary parses a dictionary file. Files ending in .csv are read as
+// CSV, where the second column is either the replacement (in map mode) or
+// an optional weight. Other files hold one value per line, ignoring blank
+// lines and lines starting with '#'.
+func readDictionary(path string, rule config.ColumnRule) (*dictionary, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var rows [][]string
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		r := csv.NewReader(file)
+		r.FieldsPerRecord = -1
+		r.TrimLeadingSpace = true
+		if rows, err = r.ReadAll(); err != nil {
+			return nil, err
+		}
+		if rule.Header && len(rows) > 0 {
+			rows = rows[1:]
+		}
+	} else {
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line != "" && !strings.HasPrefix(line, "#") {
+				rows = append(rows, []string{line})
+			}
+		}
+		if err := scanner.Err(); err != nil && err != io.EOF {
+			return nil, err
+		}
+	}
+
+	dict := &dictionary{}
+	if rule.Map {
+		dict.mapping = make(map[string]string, len(rows))
+	}
+	var total float64
+	weighted := false
+	for i, row := range rows {
+		if len(row) == 0 || row[0] == "" {
+			continue
+		}
+		switch {
+		case rule.Map:
+			if len(row) < 2 {
+				return nil, fmt.Errorf("row %d: map mode needs an original and a replacement", i+1)
+			}
+			dict.mapping[row[0]] = row[1]
+			dict.values = append(dict.values, row[1])
+		case len(row) > 1 && strings.TrimSpace(row[1]) != "":
+			weight, err := strconv.ParseFloat(strings.TrimSpace(row[1]), 64)
+			if err != nil || weight < 0 {
+				return nil, fmt.Errorf("row %d: invalid weight %q", i+1, row[1])
+			}
+			weighted = true
+			total += weight
+			dict.values = append(dict.values, row[0])
+			dict.cumulative = append(dict.cumulative, total)
+		default:
+			total++
+			dict.values = append(dict.values, row[0])
+			dict.cumulative = append(dict.cumulative, total)
+		}
+	}
+	if len(dict.values) == 0 {
+		return nil, fmt.Errorf("no values")
+	}
+	if !weighted {
+		dict.cumulative = nil
+	} else if total == 0 {
+		return nil, fmt.Errorf("weights add up to zero")
+	}
+	return dict, nil
+}
+
+// dictionaryValue replaces a value with an entry from the rule's file. In
+// map mode known values get their mapped replacement, and others an entry
+// picked from the replacements.
+func dictionaryValue(f *gofakeit.Faker, in *Input) (any, error) {
+	dict, err := loadDictionary(in.Config, in.Rule)
+	if err != nil {
+		return nil, err
+	}
+	original := stringValue(in.Value)
+	if replacement, ok := dict.mapping[original]; ok {
+		return replacement, nil
+	}
+	if in.Rule.Deterministic && in.Config.Key == "" {
+		f = seededFaker(secretKey(in.Config), "dictionary", original)
+	}
+	return dict.pick(f), nil
+}
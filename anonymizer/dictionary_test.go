@@ -0,0 +1,102 @@
+package anonymizer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andys/new_names/config"
+	"github.com/andys/new_names/db"
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/frankban/quicktest"
+)
+
+func writeDictionary(c *quicktest.C, name, content string) string {
+	path := filepath.Join(c.TempDir(), name)
+	c.Assert(os.WriteFile(path, []byte(content), 0o644), quicktest.IsNil)
+	return path
+}
+
+func TestReadDictionary_TextFile(t *testing.T) {
+	c := quicktest.New(t)
+	path := writeDictionary(c, "hospitals.txt", "# curated by QA\nSt Mary's\n\n  General Hospital  \nRoyal Infirmary\n")
+
+	dict, err := readDictionary(path, config.ColumnRule{})
+	c.Assert(err, quicktest.IsNil)
+	c.Assert(dict.values, quicktest.DeepEquals, []string{"St Mary's", "General Hospital", "Royal Infirmary"})
+	c.Assert(dict.cumulative, quicktest.IsNil)
+}
+
+func TestReadDictionary_WeightedCSV(t *testing.T) {
+	c := quicktest.New(t)
+	path := writeDictionary(c, "skus.csv", "sku,weight\nSKU-001,9\nSKU-002,1\nSKU-003,0\n")
+
+	dict, err := readDictionary(path, config.ColumnRule{Header: true})
+	c.Assert(err, quicktest.IsNil)
+	c.Assert(dict.cumulative, quicktest.DeepEquals, []float64{9, 10, 10})
+
+	f := gofakeit.New(11)
+	counts := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		counts[dict.pick(f)]++
+	}
+	c.Assert(counts["SKU-003"], quicktest.Equals, 0)
+	c.Assert(counts["SKU-001"] > 800 && counts["SKU-002"] > 50, quicktest.IsTrue, quicktest.Commentf("%v", counts))
+
+	_, err = readDictionary(path, config.ColumnRule{})
+	c.Assert(err, quicktest.ErrorMatches, `row 1: invalid weight "weight"`)
+}
+
+func TestAnonymize_Dictionary(t *testing.T) {
+	c := quicktest.New(t)
+	dir := c.TempDir()
+	c.Assert(os.WriteFile(filepath.Join(dir, "hospitals.txt"), []byte("Alpha\nBravo\nCharlie\nDelta\n"), 0o644), quicktest.IsNil)
+	c.Assert(os.WriteFile(filepath.Join(dir, "sites.csv"), []byte("Leeds,Site A\nYork,Site B\n"), 0o644), quicktest.IsNil)
+
+	schema := &db.TableSchema{
+		Name:    "visits",
+		Columns: []db.ColumnSchema{{Name: "hospital_name", Type: "varchar"}, {Name: "site", Type: "varchar"}},
+	}
+	cfg := &config.Config{
+		ConfigFile:      filepath.Join(dir, "new_names.conf"),
+		AnonymizeFields: map[string][]string{"visits": {"hospital_name", "site"}},
+		ColumnRules: map[string]map[string]config.ColumnRule{"visits": {
+			"hospital_name": {Strategy: "dictionary", File: "hospitals.txt", Deterministic: true},
+			"site":          {Strategy: "dictionary", File: "sites.csv", Map: true},
+		}},
+	}
+	c.Assert(Validate(cfg, []db.TableSchema{*schema}), quicktest.IsNil)
+
+	var first any
+	for i := 0; i < 10; i++ {
+		row := &Row{Schema: schema, Data: map[string]interface{}{"hospital_name": "St Elsewhere", "site": "York"}}
+		c.Assert(Anonymize(row, cfg), quicktest.IsNil)
+		c.Assert([]string{"Alpha", "Bravo", "Charlie", "Delta"}, quicktest.Contains, row.Data["hospital_name"])
+		if first == nil {
+			first = row.Data["hospital_name"]
+		}
+		c.Assert(row.Data["hospital_name"], quicktest.Equals, first)
+		c.Assert(row.Data["site"], quicktest.Equals, "Site B")
+	}
+
+	// Values missing from the map get one of its replacements
+	row := &Row{Schema: schema, Data: map[string]interface{}{"hospital_name": "x", "site": "Hull"}}
+	c.Assert(Anonymize(row, cfg), quicktest.IsNil)
+	c.Assert([]string{"Site A", "Site B"}, quicktest.Contains, row.Data["site"])
+}
+
+func TestValidate_Dictionary(t *testing.T) {
+	c := quicktest.New(t)
+	schemas := []db.TableSchema{{Name: "visits", Columns: []db.ColumnSchema{{Name: "site", Type: "varchar"}}}}
+	rule := func(r config.ColumnRule) *config.Config {
+		return &config.Config{ColumnRules: map[string]map[string]config.ColumnRule{"visits": {"site": r}}}
+	}
+
+	c.Assert(Validate(rule(config.ColumnRule{Strategy: "dictionary"}), schemas), quicktest.ErrorMatches, `column visits.site: dictionary strategy requires a file`)
+	c.Assert(Validate(rule(config.ColumnRule{Strategy: "dictionary", File: filepath.Join(c.TempDir(), "missing.txt")}), schemas),
+		quicktest.ErrorMatches, `column visits.site: dictionary .*missing.txt: open .*: no such file or directory`)
+
+	path := writeDictionary(c, "sites.csv", "Leeds\n")
+	c.Assert(Validate(rule(config.ColumnRule{Strategy: "dictionary", File: path, Map: true}), schemas),
+		quicktest.ErrorMatches, `column visits.site: dictionary .*: row 1: map mode needs an original and a replacement`)
+}
@@ -0,0 +1,128 @@
+package anonymizer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/andys/new_names/config"
+	"github.com/andys/new_names/db"
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// Policies for NULL, blank and zero values
+const (
+	emptyPreserve = "preserve" // Leave them as they are
+	emptyReplace  = "replace"  // Replace them like any other value, filling every NULL
+	emptyFill     = "fill"     // Replace blanks and zeros, and fill a share of NULLs
+)
+
+// checkEmptyPolicy checks an empty value policy and its fill rate
+func checkEmptyPolicy(policy string, fillRate *float64) error {
+	switch policy {
+	case "", emptyPreserve, emptyReplace, emptyFill:
+	default:
+		return fmt.Errorf("empty policy %q must be preserve, replace or fill", policy)
+	}
+	if fillRate != nil && (*fillRate < 0 || *fillRate > 1) {
+		return fmt.Errorf("fill_rate must be between 0 and 1, not %g", *fillRate)
+	}
+	return nil
+}
+
+// emptyPolicy returns the policy for a column's empty values and the share
+// of NULLs it fills. Rules override the global settings.
+func emptyPolicy(cfg *config.Config, rule config.ColumnRule) (string, float64) {
+	policy := rule.Empty
+	if policy == "" {
+		policy = cfg.Empty
+	}
+	if policy == "" {
+		policy = emptyPreserve
+	}
+	fillRate := rule.FillRate
+	if fillRate == nil {
+		fillRate = cfg.FillRate
+	}
+	if fillRate == nil {
+		return policy, 0
+	}
+	return policy, *fillRate
+}
+
+// replacesEmpty reports whether an empty value of col is to be replaced in
+// this row. Whether a NULL is filled is decided per row, deterministically
+// when a key is configured.
+func replacesEmpty(cfg *config.Config, state *rowState, col db.ColumnSchema, rule config.ColumnRule, val any) bool {
+	policy, fillRate := emptyPolicy(cfg, rule)
+	switch policy {
+	case emptyReplace:
+		return true
+	case emptyFill:
+		if val != nil {
+			return true
+		}
+		return rowFaker(cfg, state, "fill", col.Name, emptySeed(state, col)).Float64() < fillRate
+	}
+	return false
+}
+
+// emptySeed stands in for an empty value when seeding its replacement.
+// Empty values carry nothing to tell them apart, so the row's identity is
+// used, or every blank in a column would get the same fake.
+func emptySeed(state *rowState, col db.ColumnSchema) string {
+	if state == nil {
+		return "\x00empty\x00" + col.Name
+	}
+	return "\x00empty\x00" + state.schema.Name + "\x00" + col.Name + "\x00" + state.rowKey()
+}
+
+// valueStrategies rework the original value, so have nothing to work with
+// when the value is empty
+var valueStrategies = map[string]bool{
+	"date_shift": true,
+	"noise":      true,
+	"mask":       true,
+	"hash":       true,
+	"tokenize":   true,
+	"scrub":      true,
+	"json":       true,
+	"array":      true,
+}
+
+// emptyRule returns the rule an empty value is replaced under. Strategies
+// that rework the original give way to a fresh value for the column's type.
+func emptyRule(col db.ColumnSchema, rule config.ColumnRule) config.ColumnRule {
+	kind := rule.Strategy
+	if kind == "dictionary" && rule.Map {
+		kind = ""
+	}
+	if kind != "" && !valueStrategies[kind] {
+		return rule
+	}
+	fresh := config.ColumnRule{Strategy: guessKind(col), Locale: rule.Locale, LocaleFrom: rule.LocaleFrom}
+	switch fresh.Strategy {
+	case "date_shift":
+		fresh.Strategy = "date"
+	case "json", "array":
+		// An empty document or array, written the same way for both
+		empty := "{}"
+		fresh.Strategy, fresh.Value = "constant", &empty
+	}
+	return fresh
+}
+
+// dateRangeStart and dateRangeEnd bound the dates generated from nothing.
+// They are fixed, not relative to today, so keyed runs are repeatable.
+var (
+	dateRangeStart = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+	dateRangeEnd   = time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC)
+)
+
+// dateValue returns a random date, as a date alone for date columns
+func dateValue(f *gofakeit.Faker, in *Input) (any, error) {
+	t := f.DateRange(dateRangeStart, dateRangeEnd).UTC()
+	if logicalType(in.Column) == db.TypeDate {
+		return t.Truncate(24 * time.Hour), nil
+	}
+	return t.Truncate(time.Second), nil
+}
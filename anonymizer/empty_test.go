@@ -0,0 +1,117 @@
+package anonymizer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andys/new_names/config"
+	"github.com/andys/new_names/db"
+	"github.com/frankban/quicktest"
+)
+
+var contactsSchema = &db.TableSchema{
+	Name:  "contacts",
+	HasID: true,
+	IDCol: "id",
+	Columns: []db.ColumnSchema{
+		{Name: "id", Type: "int", IsID: true},
+		{Name: "phone", Type: "varchar", Nullable: true},
+		{Name: "visits", Type: "int"},
+		{Name: "born", Type: "date", Nullable: true},
+	},
+}
+
+func emptyConfig(global string, rules map[string]config.ColumnRule) *config.Config {
+	return &config.Config{
+		Empty:           global,
+		AnonymizeFields: map[string][]string{"contacts": {"phone", "visits", "born"}},
+		ColumnRules:     map[string]map[string]config.ColumnRule{"contacts": rules},
+	}
+}
+
+func anonymizeContact(c *quicktest.C, cfg *config.Config, data map[string]any) map[string]any {
+	row := &Row{Schema: contactsSchema, Data: data}
+	c.Assert(Anonymize(row, cfg), quicktest.IsNil)
+	return row.Data
+}
+
+func TestAnonymize_PreservesEmptyValuesByDefault(t *testing.T) {
+	c := quicktest.New(t)
+	data := anonymizeContact(c, emptyConfig("", nil), map[string]any{"id": 1, "phone": "  ", "visits": int64(0), "born": nil})
+	c.Assert(data, quicktest.DeepEquals, map[string]any{"id": 1, "phone": "  ", "visits": int64(0), "born": nil})
+}
+
+func TestAnonymize_ReplacesEmptyValues(t *testing.T) {
+	c := quicktest.New(t)
+	cfg := emptyConfig("replace", map[string]config.ColumnRule{
+		"phone": {Strategy: "mask"},
+		"born":  {Strategy: "date_shift"},
+	})
+	data := anonymizeContact(c, cfg, map[string]any{"id": 1, "phone": nil, "visits": int64(0), "born": nil})
+	c.Assert(data["phone"], quicktest.Not(quicktest.IsNil))
+	c.Assert(isEmpty(data["phone"]), quicktest.IsFalse)
+	_, isInt := data["visits"].(int64)
+	c.Assert(isInt, quicktest.IsTrue)
+	born, ok := data["born"].(time.Time)
+	c.Assert(ok, quicktest.IsTrue)
+	c.Assert(born.After(dateRangeStart) && born.Before(dateRangeEnd), quicktest.IsTrue)
+	c.Assert(born, quicktest.Equals, born.Truncate(24*time.Hour))
+}
+
+func TestAnonymize_ColumnPolicyOverridesGlobal(t *testing.T) {
+	c := quicktest.New(t)
+	cfg := emptyConfig("replace", map[string]config.ColumnRule{"visits": {Empty: "preserve"}})
+	data := anonymizeContact(c, cfg, map[string]any{"id": 1, "phone": "", "visits": int64(0), "born": nil})
+	c.Assert(data["visits"], quicktest.Equals, int64(0))
+	c.Assert(data["phone"], quicktest.Not(quicktest.Equals), "")
+}
+
+func TestAnonymize_FillsShareOfNulls(t *testing.T) {
+	c := quicktest.New(t)
+	rate := 0.3
+	cfg := emptyConfig("", map[string]config.ColumnRule{"phone": {Strategy: "phone", Empty: "fill", FillRate: &rate}})
+	cfg.Key = "secret"
+
+	filled := 0
+	for id := 0; id < 1000; id++ {
+		data := anonymizeContact(c, cfg, map[string]any{"id": id, "phone": nil, "visits": int64(1), "born": nil})
+		if data["phone"] != nil {
+			filled++
+		}
+	}
+	c.Assert(filled > 220 && filled < 380, quicktest.IsTrue, quicktest.Commentf("filled %d of 1000", filled))
+
+	// Blanks are always replaced under fill
+	data := anonymizeContact(c, cfg, map[string]any{"id": 1, "phone": "", "visits": int64(1), "born": nil})
+	c.Assert(data["phone"], quicktest.Not(quicktest.Equals), "")
+}
+
+func TestAnonymize_EmptyValuesSeededByRow(t *testing.T) {
+	c := quicktest.New(t)
+	cfg := emptyConfig("replace", map[string]config.ColumnRule{"phone": {Strategy: "phone"}})
+	cfg.Key = "secret"
+
+	first := anonymizeContact(c, cfg, map[string]any{"id": 1, "phone": nil, "visits": int64(1), "born": nil})
+	again := anonymizeContact(c, cfg, map[string]any{"id": 1, "phone": nil, "visits": int64(1), "born": nil})
+	other := anonymizeContact(c, cfg, map[string]any{"id": 2, "phone": nil, "visits": int64(1), "born": nil})
+	c.Assert(again["phone"], quicktest.Equals, first["phone"])
+	c.Assert(other["phone"], quicktest.Not(quicktest.Equals), first["phone"])
+}
+
+func TestValidate_EmptyPolicy(t *testing.T) {
+	c := quicktest.New(t)
+	c.Assert(Validate(emptyConfig("sometimes", nil), []db.TableSchema{*contactsSchema}), quicktest.ErrorMatches,
+		`empty policy "sometimes" must be preserve, replace or fill`)
+
+	rate := 1.5
+	cfg := emptyConfig("", map[string]config.ColumnRule{"phone": {Empty: "fill", FillRate: &rate}})
+	c.Assert(Validate(cfg, []db.TableSchema{*contactsSchema}), quicktest.ErrorMatches,
+		`column contacts.phone: fill_rate must be between 0 and 1, not 1.5`)
+}
+
+func TestColumnEmpty_Bytes(t *testing.T) {
+	c := quicktest.New(t)
+	c.Assert(columnEmpty(db.ColumnSchema{Type: "varchar"}, []byte(" ")), quicktest.IsTrue)
+	c.Assert(columnEmpty(db.ColumnSchema{Type: "blob"}, []byte(" ")), quicktest.IsFalse)
+	c.Assert(columnEmpty(db.ColumnSchema{Type: "blob"}, []byte{}), quicktest.IsTrue)
+}
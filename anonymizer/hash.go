@@ -0,0 +1,28 @@
+package anonymizer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"strings"
+)
+
+// hashEncodings are the supported output encodings for the hash strategy
+var hashEncodings = map[string]func([]byte) string{
+	"":    hex.EncodeToString,
+	"hex": hex.EncodeToString,
+	"base32": func(b []byte) string {
+		return strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))
+	},
+}
+
+// HashValue returns an HMAC-SHA256 pseudonym of value, keyed with salt and
+// rendered in the given encoding ("hex" or "base32"). Equal inputs always give
+// equal pseudonyms, so hashed columns still join across tables and exports,
+// but without the salt the originals can't be recovered by hashing guesses.
+func HashValue(salt, value, encoding string) string {
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(value))
+	return hashEncodings[encoding](mac.Sum(nil))
+}
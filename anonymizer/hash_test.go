@@ -0,0 +1,22 @@
+package anonymizer
+
+import (
+	"testing"
+
+	"github.com/frankban/quicktest"
+)
+
+func TestHashValue_StableAndSalted(t *testing.T) {
+	c := quicktest.New(t)
+
+	a := HashValue("pepper", "CUST-0001", "hex")
+	c.Assert(a, quicktest.Matches, `[0-9a-f]{64}`)
+	c.Assert(HashValue("pepper", "CUST-0001", ""), quicktest.Equals, a)
+	c.Assert(HashValue("other", "CUST-0001", "hex"), quicktest.Not(quicktest.Equals), a)
+	c.Assert(HashValue("pepper", "CUST-0002", "hex"), quicktest.Not(quicktest.Equals), a)
+}
+
+func TestHashValue_Base32(t *testing.T) {
+	c := quicktest.New(t)
+	c.Assert(HashValue("pepper", "CUST-0001", "base32"), quicktest.Matches, `[a-z2-7]{52}`)
+}
@@ -0,0 +1,253 @@
+package anonymizer
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// digitsOf returns the ASCII digits in s
+func digitsOf(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// randomDigits returns n random digits
+func randomDigits(f *gofakeit.Faker, n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte('0' + f.IntN(10))
+	}
+	return string(b)
+}
+
+// relayout puts the characters of value into the places the original had
+// its letters and digits, keeping its separators, so "4111-1111-..." stays
+// dashed. If the counts differ the plain value is returned.
+func relayout(original, value string) string {
+	slots := 0
+	for _, r := range original {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			slots++
+		}
+	}
+	if slots != len(value) {
+		return value
+	}
+	var b strings.Builder
+	i := 0
+	for _, r := range original {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteByte(value[i])
+			i++
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// luhnCheckDigit returns the digit that makes payload pass the Luhn check
+func luhnCheckDigit(payload string) byte {
+	sum, double := 0, true
+	for i := len(payload) - 1; i >= 0; i-- {
+		d := int(payload[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// cardBINLength is how many leading digits of a card number identify its
+// issuer and brand, and are kept
+const cardBINLength = 6
+
+// CreditCard returns a Luhn-valid card number of the same length and brand
+// as the original, keeping its issuer (BIN) and separators. Originals that
+// aren't card numbers get a 16-digit Visa number.
+func CreditCard(f *gofakeit.Faker, original string) string {
+	digits := digitsOf(original)
+	if len(digits) < 12 || len(digits) > 19 {
+		digits = "4" + randomDigits(f, 15)
+	}
+	payload := digits[:cardBINLength] + randomDigits(f, len(digits)-cardBINLength-1)
+	return relayout(original, payload+string(luhnCheckDigit(payload)))
+}
+
+// ibanLengths are the IBAN lengths of common countries, used when the
+// original doesn't give one
+var ibanLengths = map[string]int{
+	"AT": 20, "BE": 16, "CH": 21, "DE": 22, "DK": 18, "ES": 24, "FI": 18, "FR": 27, "GB": 22,
+	"IE": 22, "IT": 27, "LU": 20, "NL": 18, "NO": 15, "PL": 28, "PT": 25, "SE": 24,
+}
+
+// ibanCheckDigits computes the two check digits of an IBAN (ISO 13616)
+func ibanCheckDigits(country, bban string) string {
+	var numeric strings.Builder
+	for _, r := range bban + country + "00" {
+		if r >= 'A' && r <= 'Z' {
+			numeric.WriteString(strconv.Itoa(int(r-'A') + 10))
+		} else {
+			numeric.WriteRune(r)
+		}
+	}
+	n, _ := new(big.Int).SetString(numeric.String(), 10)
+	check := 98 - new(big.Int).Mod(n, big.NewInt(97)).Int64()
+	return strconv.FormatInt(check+100, 10)[1:]
+}
+
+// ibanValid reports whether an IBAN's check digits are right
+func ibanValid(iban string) bool {
+	iban = strings.ToUpper(strings.ReplaceAll(iban, " ", ""))
+	if len(iban) < 5 {
+		return false
+	}
+	for _, r := range iban {
+		if !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return ibanCheckDigits(iban[:2], iban[4:]) == iban[2:4]
+}
+
+// IBAN returns an IBAN with valid check digits in the original's country
+// and format: its account part has letters and digits where the
+// original's had them. Originals that aren't IBANs get a German one.
+func IBAN(f *gofakeit.Faker, original string) string {
+	compact := strings.ToUpper(strings.ReplaceAll(original, " ", ""))
+	country, layout := "DE", strings.Repeat("0", ibanLengths["DE"]-4)
+	if len(compact) > 4 && compact[0] >= 'A' && compact[0] <= 'Z' && compact[1] >= 'A' && compact[1] <= 'Z' {
+		country, layout = compact[:2], compact[4:]
+	} else {
+		original = ""
+	}
+
+	bban := []byte(layout)
+	for i, c := range bban {
+		switch {
+		case c >= 'A' && c <= 'Z':
+			bban[i] = byte('A' + f.IntN(26))
+		default:
+			bban[i] = byte('0' + f.IntN(10))
+		}
+	}
+	iban := country + ibanCheckDigits(country, string(bban)) + string(bban)
+	if original == "" {
+		return iban
+	}
+	return relayout(original, iban)
+}
+
+// SSN returns a US Social Security number that follows the allocation
+// rules: no area 000, 666 or 900-999, no group 00 and no serial 0000.
+// It is dashed unless the original was nine bare digits.
+func SSN(f *gofakeit.Faker, original string) string {
+	area := f.IntRange(1, 899)
+	if area == 666 {
+		area = 665
+	}
+	ssn := strconv.Itoa(1000 + area)[1:] + strconv.Itoa(100 + f.IntRange(1, 99))[1:] + strconv.Itoa(10000 + f.IntRange(1, 9999))[1:]
+	if strings.TrimSpace(original) == digitsOf(original) && len(digitsOf(original)) == 9 {
+		return ssn
+	}
+	return ssn[:3] + "-" + ssn[3:5] + "-" + ssn[5:]
+}
+
+// NINO returns a UK National Insurance number such as "QQ 12 34 56 C",
+// using only the prefixes HMRC allocates. It keeps the original's spacing.
+func NINO(f *gofakeit.Faker, original string) string {
+	const (
+		firstLetters  = "ABCEGHJKLMNOPRSTWXYZ"
+		secondLetters = "ABCEGHJKLMNPRSTWXYZ"
+	)
+	var prefix string
+	for {
+		prefix = string(firstLetters[f.IntN(len(firstLetters))]) + string(secondLetters[f.IntN(len(secondLetters))])
+		switch prefix {
+		case "BG", "GB", "NK", "KN", "TN", "NT", "ZZ":
+			continue
+		}
+		break
+	}
+	nino := prefix + randomDigits(f, 6) + string("ABCD"[f.IntN(4)])
+	if original == "" || !strings.Contains(original, " ") {
+		return nino
+	}
+	return relayout(original, nino)
+}
+
+// CanadianSIN returns a Luhn-valid Canadian Social Insurance Number,
+// keeping the original's separators. Temporary (9) and unused (0, 8)
+// first digits are avoided.
+func CanadianSIN(f *gofakeit.Faker, original string) string {
+	payload := strconv.Itoa(f.IntRange(1, 7)) + randomDigits(f, 7)
+	sin := payload + string(luhnCheckDigit(payload))
+	if len(digitsOf(original)) != 9 {
+		return sin[:3] + "-" + sin[3:6] + "-" + sin[6:]
+	}
+	return relayout(original, sin)
+}
+
+// germanTaxIDCheckDigit computes the ISO 7064 MOD 11,10 check digit of a
+// German tax identification number
+func germanTaxIDCheckDigit(payload string) byte {
+	product := 10
+	for _, c := range payload {
+		sum := (int(c-'0') + product) % 10
+		if sum == 0 {
+			sum = 10
+		}
+		product = sum * 2 % 11
+	}
+	check := 11 - product
+	if check == 10 {
+		check = 0
+	}
+	return byte('0' + check)
+}
+
+// GermanTaxID returns an 11-digit German tax identification number
+// (Steuer-IdNr). Its first ten digits contain exactly one digit twice and
+// no zero in front, as issued numbers do, and the last is a check digit.
+func GermanTaxID(f *gofakeit.Faker, original string) string {
+	digits := []byte("0123456789")
+	f.ShuffleAnySlice(digits)
+	if digits[0] == '0' {
+		digits[0], digits[1] = digits[1], digits[0]
+	}
+	// Drop one digit and repeat another in its place, away from the front
+	repeated := 1 + f.IntN(8)
+	digits[9] = digits[repeated]
+	payload := string(digits)
+	return relayout(original, payload+string(germanTaxIDCheckDigit(payload)))
+}
+
+// identifierGenerators maps strategy names to the generators of checksummed identifiers
+var identifierGenerators = map[string]func(f *gofakeit.Faker, original string) string{
+	"credit_card": CreditCard,
+	"iban":        IBAN,
+	"ssn":         SSN,
+	"nino":        NINO,
+	"ca_sin":      CanadianSIN,
+	"de_tax_id":   GermanTaxID,
+}
+
+// identifierTransformer adapts an identifier generator to a transformer
+func identifierTransformer(generate func(f *gofakeit.Faker, original string) string) TransformerFunc {
+	return func(f *gofakeit.Faker, in *Input) (any, error) {
+		return generate(f, stringValue(in.Value)), nil
+	}
+}
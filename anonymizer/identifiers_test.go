@@ -0,0 +1,119 @@
+package anonymizer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/andys/new_names/config"
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/frankban/quicktest"
+)
+
+func TestCreditCard_KeepsBINAndPassesLuhn(t *testing.T) {
+	c := quicktest.New(t)
+	f := gofakeit.New(1)
+
+	for i := 0; i < 50; i++ {
+		card := CreditCard(f, "5425-2334-3010-9903")
+		c.Assert(card, quicktest.Matches, `5425-23\d\d-\d{4}-\d{4}`)
+		c.Assert(luhnValid(card), quicktest.IsTrue, quicktest.Commentf(card))
+
+		amex := CreditCard(f, "378282246310005")
+		c.Assert(amex, quicktest.Matches, `378282\d{9}`)
+		c.Assert(luhnValid(amex), quicktest.IsTrue)
+	}
+	c.Assert(CreditCard(f, "n/a"), quicktest.Matches, `4\d{15}`)
+}
+
+func TestIBAN_KeepsCountryAndFormat(t *testing.T) {
+	c := quicktest.New(t)
+	f := gofakeit.New(2)
+	c.Assert(ibanValid("GB82 WEST 1234 5698 7654 32"), quicktest.IsTrue)
+	c.Assert(ibanValid("GB82 WEST 1234 5698 7654 33"), quicktest.IsFalse)
+
+	for i := 0; i < 50; i++ {
+		gb := IBAN(f, "GB82 WEST 1234 5698 7654 32")
+		c.Assert(gb, quicktest.Matches, `GB\d\d [A-Z]{4} \d{4} \d{4} \d{4} \d\d`)
+		c.Assert(ibanValid(gb), quicktest.IsTrue, quicktest.Commentf(gb))
+
+		fr := IBAN(f, "FR1420041010050500013M02606")
+		c.Assert(fr, quicktest.Matches, `FR\d{2}\d{10}[A-Z0-9]{11}\d{2}`)
+		c.Assert(ibanValid(fr), quicktest.IsTrue, quicktest.Commentf(fr))
+	}
+	de := IBAN(f, "")
+	c.Assert(de, quicktest.Matches, `DE\d{20}`)
+	c.Assert(ibanValid(de), quicktest.IsTrue)
+}
+
+func TestSSN(t *testing.T) {
+	c := quicktest.New(t)
+	f := gofakeit.New(3)
+
+	for i := 0; i < 200; i++ {
+		ssn := SSN(f, "078-05-1120")
+		c.Assert(ssn, quicktest.Matches, `\d{3}-\d{2}-\d{4}`)
+		area := ssn[:3]
+		c.Assert(area != "000" && area != "666" && area[0] != '9', quicktest.IsTrue, quicktest.Commentf(ssn))
+		c.Assert(ssn[4:6] != "00" && ssn[7:] != "0000", quicktest.IsTrue, quicktest.Commentf(ssn))
+	}
+	c.Assert(SSN(f, "078051120"), quicktest.Matches, `\d{9}`)
+}
+
+func TestNINO(t *testing.T) {
+	c := quicktest.New(t)
+	f := gofakeit.New(4)
+
+	for i := 0; i < 200; i++ {
+		nino := NINO(f, "")
+		c.Assert(nino, quicktest.Matches, `[A-CEGHJ-PR-TW-Z][A-CEGHJ-NPR-TW-Z]\d{6}[A-D]`)
+		c.Assert([]string{"BG", "GB", "NK", "KN", "TN", "NT", "ZZ"}, quicktest.Not(quicktest.Contains), nino[:2])
+	}
+	c.Assert(NINO(f, "QQ 12 34 56 C"), quicktest.Matches, `[A-Z]{2} \d\d \d\d \d\d [A-D]`)
+}
+
+func TestCanadianSIN(t *testing.T) {
+	c := quicktest.New(t)
+	f := gofakeit.New(5)
+
+	for i := 0; i < 50; i++ {
+		sin := CanadianSIN(f, "046 454 286")
+		c.Assert(sin, quicktest.Matches, `[1-7]\d\d \d{3} \d{3}`)
+		c.Assert(luhnValid(sin), quicktest.IsTrue, quicktest.Commentf(sin))
+	}
+	c.Assert(CanadianSIN(f, ""), quicktest.Matches, `\d{3}-\d{3}-\d{3}`)
+}
+
+func TestGermanTaxID(t *testing.T) {
+	c := quicktest.New(t)
+	f := gofakeit.New(6)
+	// A published example number
+	c.Assert(string(germanTaxIDCheckDigit("8609574271")), quicktest.Equals, "9")
+
+	for i := 0; i < 100; i++ {
+		id := GermanTaxID(f, "86095742719")
+		c.Assert(id, quicktest.Matches, `[1-9]\d{10}`)
+		c.Assert(germanTaxIDCheckDigit(id[:10]), quicktest.Equals, id[10])
+
+		counts := map[rune]int{}
+		for _, d := range id[:10] {
+			counts[d]++
+		}
+		c.Assert(counts, quicktest.HasLen, 9, quicktest.Commentf(id))
+	}
+	c.Assert(GermanTaxID(f, "86 095 742 719"), quicktest.Matches, `\d\d \d{3} \d{3} \d{3}`)
+}
+
+func TestAnonymize_ScrubUsesValidIdentifiers(t *testing.T) {
+	c := quicktest.New(t)
+	row := &Row{Schema: ticketSchema, Data: map[string]interface{}{
+		"body": "Card 4111 1111 1111 1111, SSN 078-05-1120, NI QQ 12 34 56 C",
+	}}
+	c.Assert(Anonymize(row, scrubConfig(config.ColumnRule{Strategy: "scrub"})), quicktest.IsNil)
+
+	body := row.Data["body"].(string)
+	card := body[len("Card ") : len("Card ")+19]
+	c.Assert(strings.HasPrefix(card, "4111 11"), quicktest.IsTrue)
+	c.Assert(luhnValid(card), quicktest.IsTrue)
+	c.Assert(body, quicktest.Matches, `Card [\d ]{19}, SSN \d{3}-\d{2}-\d{4}, NI [A-Z]{2} \d\d \d\d \d\d [A-D]`)
+	c.Assert(strings.Contains(body, "078-05-1120"), quicktest.IsFalse)
+}
@@ -0,0 +1,299 @@
+package anonymizer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/andys/new_names/config"
+	"github.com/andys/new_names/db"
+)
+
+// pathStep is one step of a JSON path: an object key, an array index, or a
+// wildcard matching every member of an object or element of an array
+type pathStep struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// parseJSONPath parses the subset of JSONPath used in path rules:
+// $.key, $.key[0], $.key[*] and $.*
+func parseJSONPath(path string) ([]pathStep, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("JSON path %q must start with $", path)
+	}
+	var steps []pathStep
+	rest := path[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			key := rest[1 : end+1]
+			if key == "" {
+				return nil, fmt.Errorf("JSON path %q has an empty key", path)
+			}
+			steps = append(steps, pathStep{key: key, wildcard: key == "*"})
+			rest = rest[end+1:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("JSON path %q has an unclosed [", path)
+			}
+			inner := rest[1:end]
+			if inner == "*" {
+				steps = append(steps, pathStep{wildcard: true})
+			} else if n, err := strconv.Atoi(inner); err == nil && n >= 0 {
+				steps = append(steps, pathStep{index: n, isIndex: true})
+			} else {
+				return nil, fmt.Errorf("JSON path %q has an invalid index [%s]", path, inner)
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("JSON path %q is invalid at %q", path, rest)
+		}
+	}
+	return steps, nil
+}
+
+// jsonObject is a decoded JSON object that remembers its key order, so a
+// rewritten document differs from the original only at the matched leaves
+type jsonObject struct {
+	keys   []string
+	values map[string]any
+}
+
+// decodeJSON decodes one JSON value, keeping object key order and number text
+func decodeJSON(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+	switch delim {
+	case '{':
+		obj := &jsonObject{values: make(map[string]any)}
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key := keyTok.(string)
+			val, err := decodeJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			if _, dup := obj.values[key]; !dup {
+				obj.keys = append(obj.keys, key)
+			}
+			obj.values[key] = val
+		}
+		_, err = dec.Token()
+		return obj, err
+	case '[':
+		arr := []any{}
+		for dec.More() {
+			val, err := decodeJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, val)
+		}
+		_, err = dec.Token()
+		return arr, err
+	}
+	return nil, fmt.Errorf("unexpected JSON delimiter %v", delim)
+}
+
+// encodeJSON writes v compactly, without escaping HTML characters
+func encodeJSON(buf *bytes.Buffer, v any) error {
+	switch t := v.(type) {
+	case *jsonObject:
+		buf.WriteByte('{')
+		for i, key := range t.keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encodeJSON(buf, key); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := encodeJSON(buf, t.values[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	case []any:
+		buf.WriteByte('[')
+		for i, elem := range t {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encodeJSON(buf, elem); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	// Encode adds a newline after each value
+	buf.Truncate(buf.Len() - 1)
+	return nil
+}
+
+// rewriteJSON applies fn to every leaf of node matched by steps
+func rewriteJSON(node any, steps []pathStep, fn func(any) (any, error)) (any, error) {
+	if len(steps) == 0 {
+		return fn(node)
+	}
+	step, rest := steps[0], steps[1:]
+	switch t := node.(type) {
+	case *jsonObject:
+		if step.isIndex {
+			return node, nil
+		}
+		for _, key := range t.keys {
+			if !step.wildcard && key != step.key {
+				continue
+			}
+			val, err := rewriteJSON(t.values[key], rest, fn)
+			if err != nil {
+				return nil, err
+			}
+			t.values[key] = val
+		}
+	case []any:
+		for i := range t {
+			if !step.wildcard && (!step.isIndex || i != step.index) {
+				continue
+			}
+			val, err := rewriteJSON(t[i], rest, fn)
+			if err != nil {
+				return nil, err
+			}
+			t[i] = val
+		}
+	}
+	return node, nil
+}
+
+// rewriteJSONStrings applies fn to every string in node, along with the key
+// of the object member holding it. Strings in arrays take the array's key.
+func rewriteJSONStrings(node any, key string, fn func(key, s string) (any, error)) (any, error) {
+	switch t := node.(type) {
+	case string:
+		return fn(key, t)
+	case *jsonObject:
+		for _, k := range t.keys {
+			val, err := rewriteJSONStrings(t.values[k], k, fn)
+			if err != nil {
+				return nil, err
+			}
+			t.values[k] = val
+		}
+	case []any:
+		for i := range t {
+			val, err := rewriteJSONStrings(t[i], key, fn)
+			if err != nil {
+				return nil, err
+			}
+			t[i] = val
+		}
+	}
+	return node, nil
+}
+
+// sortedPaths returns the paths of a JSON rule in a stable order
+func sortedPaths(paths map[string]config.ColumnRule) []string {
+	keys := make([]string, 0, len(paths))
+	for path := range paths {
+		keys = append(keys, path)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// leafColumn describes a JSON leaf as a column, so it can be anonymized like one
+func leafColumn(col db.ColumnSchema, path string) db.ColumnSchema {
+	return db.ColumnSchema{Name: col.Name + "[" + path + "]", Type: "json", Nullable: true}
+}
+
+// isLeafColumn reports whether col stands for the values at a path inside
+// a JSON document, as made by leafColumn
+func isLeafColumn(col db.ColumnSchema) bool {
+	return col.Type == "json" && strings.HasSuffix(col.Name, "]")
+}
+
+// anonymizeJSON rewrites the leaves of a JSON document matched by the rule's
+// paths, each with its own rule, and leaves the rest of the document intact.
+// Without paths, as for JSON columns given no rule, every string is replaced
+// by a value guessed from its key, keeping the document's structure.
+func anonymizeJSON(in *Input) (any, error) {
+	dec := json.NewDecoder(strings.NewReader(stringValue(in.Value)))
+	dec.UseNumber()
+	doc, err := decodeJSON(dec)
+	if err != nil {
+		return nil, badValue(fmt.Errorf("invalid JSON: %w", err))
+	}
+
+	if len(in.Rule.Paths) == 0 {
+		doc, err = rewriteJSONStrings(doc, "", func(key, s string) (any, error) {
+			if isEmpty(s) {
+				return s, nil
+			}
+			keyCol := db.ColumnSchema{Name: key, Nullable: true}
+			return transform(in.Config, in.state, in.Row, keyCol, config.ColumnRule{}, s)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, path := range sortedPaths(in.Rule.Paths) {
+		leafRule := in.Rule.Paths[path]
+		steps, err := parseJSONPath(path)
+		if err != nil {
+			return nil, err
+		}
+		leafCol := leafColumn(in.Column, path)
+		doc, err = rewriteJSON(doc, steps, func(leaf any) (any, error) {
+			if !fixedStrategies[leafRule.Strategy] && isEmpty(leaf) {
+				return leaf, nil
+			}
+			if n, ok := leaf.(json.Number); ok {
+				// Numeric strategies expect Go numbers
+				if i, err := n.Int64(); err == nil {
+					leaf = i
+				} else if f, err := n.Float64(); err == nil {
+					leaf = f
+				}
+			}
+			return transform(in.Config, in.state, in.Row, leafCol, leafRule, leaf)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := encodeJSON(&buf, doc); err != nil {
+		return nil, err
+	}
+	return buf.String(), nil
+}
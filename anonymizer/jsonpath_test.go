@@ -0,0 +1,100 @@
+package anonymizer
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/andys/new_names/config"
+	"github.com/andys/new_names/db"
+	"github.com/frankban/quicktest"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	c := quicktest.New(t)
+
+	steps, err := parseJSONPath("$.addresses[*].street")
+	c.Assert(err, quicktest.IsNil)
+	c.Assert(steps, quicktest.CmpEquals(cmp.AllowUnexported(pathStep{})), []pathStep{
+		{key: "addresses"},
+		{wildcard: true},
+		{key: "street"},
+	})
+
+	steps, err = parseJSONPath("$.phones[1]")
+	c.Assert(err, quicktest.IsNil)
+	c.Assert(steps, quicktest.CmpEquals(cmp.AllowUnexported(pathStep{})), []pathStep{{key: "phones"}, {index: 1, isIndex: true}})
+
+	_, err = parseJSONPath("contact.email")
+	c.Assert(err, quicktest.ErrorMatches, `JSON path "contact.email" must start with \$`)
+	_, err = parseJSONPath("$.a[x]")
+	c.Assert(err, quicktest.ErrorMatches, `JSON path "\$.a\[x\]" has an invalid index \[x\]`)
+}
+
+func TestAnonymize_JSONPathsRewriteOnlyMatchedLeaves(t *testing.T) {
+	c := quicktest.New(t)
+	schema := &db.TableSchema{
+		Name:    "customers",
+		Columns: []db.ColumnSchema{{Name: "profile", Type: "jsonb"}},
+	}
+	doc := `{"name":"x","contact":{"email":"a@b.com","phone":null},"addresses":[{"street":"1 Real St","zip":"12345"},{"street":"2 Real St","zip":"67890"}],"score":42,"note":"<b>&</b>"}`
+	row := &Row{Schema: schema, Data: map[string]interface{}{"profile": []byte(doc)}}
+	cfg := &config.Config{
+		AnonymizeFields: map[string][]string{"customers": {"profile"}},
+		ColumnRules: map[string]map[string]config.ColumnRule{
+			"customers": {"profile": {Strategy: "json", Paths: map[string]config.ColumnRule{
+				"$.contact.email":       {Strategy: "redact"},
+				"$.contact.phone":       {Strategy: "phone"},
+				"$.addresses[*].street": {Strategy: "street_address"},
+			}}},
+		},
+	}
+
+	c.Assert(Anonymize(row, cfg), quicktest.IsNil)
+
+	out := row.Data["profile"].(string)
+	c.Assert(out, quicktest.Matches, `\{"name":"x","contact":\{"email":"\[REDACTED\]","phone":null\},"addresses":\[\{"street":"[^"]+","zip":"12345"\},\{"street":"[^"]+","zip":"67890"\}\],"score":42,"note":"<b>&</b>"\}`)
+	var parsed struct {
+		Addresses []struct{ Street string }
+	}
+	c.Assert(json.Unmarshal([]byte(out), &parsed), quicktest.IsNil)
+	c.Assert(parsed.Addresses[0].Street, quicktest.Not(quicktest.Equals), "1 Real St")
+}
+
+func TestAnonymize_InvalidJSON(t *testing.T) {
+	c := quicktest.New(t)
+	schema := &db.TableSchema{
+		Name:    "customers",
+		Columns: []db.ColumnSchema{{Name: "profile", Type: "json"}},
+	}
+	row := &Row{Schema: schema, Data: map[string]interface{}{"profile": "{not json"}}
+	cfg := &config.Config{
+		AnonymizeFields: map[string][]string{"customers": {"profile"}},
+		ColumnRules: map[string]map[string]config.ColumnRule{
+			"customers": {"profile": {Strategy: "json", Paths: map[string]config.ColumnRule{
+				"$.email": {Strategy: "email"},
+			}}},
+		},
+	}
+
+	err := Anonymize(row, cfg)
+	c.Assert(err, quicktest.ErrorMatches, "column profile: invalid JSON: .*")
+	c.Assert(err, quicktest.ErrorIs, ErrBadValue)
+}
+
+func TestValidate_JSONPathRules(t *testing.T) {
+	c := quicktest.New(t)
+	schemas := []db.TableSchema{{
+		Name:    "customers",
+		Columns: []db.ColumnSchema{{Name: "profile", Type: "json"}},
+	}}
+	cfg := &config.Config{
+		ColumnRules: map[string]map[string]config.ColumnRule{
+			"customers": {"profile": {Strategy: "json", Paths: map[string]config.ColumnRule{
+				"$.email": {Strategy: "emial"},
+			}}},
+		},
+	}
+
+	c.Assert(Validate(cfg, schemas), quicktest.ErrorMatches, `column customers.profile\[\$.email\]: unknown anonymization strategy "emial"`)
+}
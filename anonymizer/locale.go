@@ -0,0 +1,333 @@
+package anonymizer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/andys/new_names/config"
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// defaultLocale is the locale of gofakeit's own data
+const defaultLocale = "en"
+
+// localName is a name together with its Latin-script spelling, which is
+// what emails and usernames are built from
+type localName struct {
+	text  string
+	latin string
+}
+
+// place is a city with the state and postcode format that go with it.
+// In postcodes each '#' stands for a random digit.
+type place struct {
+	city     string
+	state    string
+	postcode string
+}
+
+// localeData is the fake data of one locale. A nil *localeData stands for
+// the default locale and generates from gofakeit's English data.
+type localeData struct {
+	femaleFirstNames []localName
+	maleFirstNames   []localName
+	lastNames        []localName
+	familyNameFirst  bool     // Full names are written family name first
+	streets          []string // Street or neighbourhood names
+	streetFormat     string   // Street address layout; %s is the street, '#' a digit
+	places           []place
+	phoneFormats     []string // Phone layouts, '#' being a digit
+	country          string
+}
+
+// latinNames builds the names of a Latin-script locale, spelling out
+// diacritics so that emails stay ASCII
+func latinNames(names ...string) []localName {
+	spell := strings.NewReplacer("ä", "ae", "ö", "oe", "ü", "ue", "Ä", "Ae", "Ö", "Oe", "Ü", "Ue", "ß", "ss")
+	out := make([]localName, len(names))
+	for i, name := range names {
+		out[i] = localName{text: name, latin: spell.Replace(name)}
+	}
+	return out
+}
+
+// locales holds the supported locales other than the default
+var locales = map[string]*localeData{
+	"de": {
+		femaleFirstNames: latinNames(
+			"Anna", "Emma", "Hannah", "Lea", "Leonie", "Lena", "Marie", "Mia", "Sophie", "Julia",
+			"Katharina", "Sabine", "Petra", "Ursula", "Jana", "Jülide", "Käthe", "Lotte",
+		),
+		maleFirstNames: latinNames(
+			"Lukas", "Leon", "Jonas", "Felix", "Maximilian", "Paul", "Tobias", "Stefan", "Thomas", "Michael",
+			"Jürgen", "Jörg", "Uwe", "Klaus", "Günter", "Björn", "Matthias", "Sören",
+		),
+		lastNames: latinNames(
+			"Müller", "Schmidt", "Schneider", "Fischer", "Weber", "Meyer", "Wagner", "Becker", "Schulz", "Hoffmann",
+			"Schäfer", "Koch", "Bauer", "Richter", "Klein", "Wolf", "Schröder", "Neumann", "Schwarz", "Zimmermann",
+			"Braun", "Krüger", "Hofmann", "Hartmann", "Lange", "Weiß", "Köhler", "Groß",
+		),
+		streets: []string{
+			"Hauptstraße", "Bahnhofstraße", "Gartenstraße", "Schulstraße", "Dorfstraße", "Bergstraße",
+			"Lindenstraße", "Kirchstraße", "Goethestraße", "Schillerstraße", "Mühlenweg", "Am Marktplatz",
+		},
+		streetFormat: "%s ##",
+		places: []place{
+			{"Berlin", "Berlin", "10###"},
+			{"Hamburg", "Hamburg", "20###"},
+			{"München", "Bayern", "80###"},
+			{"Köln", "Nordrhein-Westfalen", "50###"},
+			{"Frankfurt am Main", "Hessen", "60###"},
+			{"Stuttgart", "Baden-Württemberg", "70###"},
+			{"Düsseldorf", "Nordrhein-Westfalen", "40###"},
+			{"Leipzig", "Sachsen", "04###"},
+			{"Dresden", "Sachsen", "01###"},
+			{"Nürnberg", "Bayern", "90###"},
+			{"Bremen", "Bremen", "28###"},
+			{"Hannover", "Niedersachsen", "30###"},
+		},
+		phoneFormats: []string{"030 ########", "089 #######", "040 ########", "0151 ########", "0170 #######", "+49 221 #######"},
+		country:      "Deutschland",
+	},
+	"ja": {
+		femaleFirstNames: []localName{
+			{"陽菜", "hina"}, {"結衣", "yui"}, {"さくら", "sakura"}, {"美咲", "misaki"}, {"葵", "aoi"}, {"花子", "hanako"},
+			{"優子", "yuko"}, {"真由美", "mayumi"}, {"恵子", "keiko"}, {"愛", "ai"}, {"明美", "akemi"}, {"由美", "yumi"},
+		},
+		maleFirstNames: []localName{
+			{"太郎", "taro"}, {"翔太", "shota"}, {"大輔", "daisuke"}, {"健太", "kenta"}, {"拓也", "takuya"}, {"蓮", "ren"},
+			{"悠斗", "yuto"}, {"大翔", "hiroto"}, {"誠", "makoto"}, {"浩", "hiroshi"}, {"直樹", "naoki"}, {"和也", "kazuya"},
+		},
+		lastNames: []localName{
+			{"佐藤", "sato"}, {"鈴木", "suzuki"}, {"高橋", "takahashi"}, {"田中", "tanaka"}, {"伊藤", "ito"},
+			{"渡辺", "watanabe"}, {"山本", "yamamoto"}, {"中村", "nakamura"}, {"小林", "kobayashi"}, {"加藤", "kato"},
+			{"吉田", "yoshida"}, {"山田", "yamada"}, {"佐々木", "sasaki"}, {"山口", "yamaguchi"}, {"松本", "matsumoto"},
+			{"井上", "inoue"}, {"木村", "kimura"}, {"林", "hayashi"}, {"斎藤", "saito"}, {"清水", "shimizu"},
+		},
+		familyNameFirst: true,
+		streets:         []string{"本町", "中央", "栄町", "緑町", "桜木町", "旭町", "若葉", "青葉台", "東町", "南町"},
+		streetFormat:    "%s#丁目#-##",
+		places: []place{
+			{"新宿区", "東京都", "160-####"},
+			{"渋谷区", "東京都", "150-####"},
+			{"世田谷区", "東京都", "154-####"},
+			{"横浜市", "神奈川県", "220-####"},
+			{"大阪市", "大阪府", "530-####"},
+			{"名古屋市", "愛知県", "450-####"},
+			{"札幌市", "北海道", "060-####"},
+			{"福岡市", "福岡県", "810-####"},
+			{"京都市", "京都府", "600-####"},
+			{"神戸市", "兵庫県", "650-####"},
+			{"仙台市", "宮城県", "980-####"},
+			{"広島市", "広島県", "730-####"},
+		},
+		phoneFormats: []string{"03-####-####", "06-####-####", "090-####-####", "080-####-####", "070-####-####"},
+		country:      "日本",
+	},
+}
+
+// usPlaces are the cities of the default locale. gofakeit's cities, states
+// and zip codes are unrelated to each other, so addresses use these instead.
+var usPlaces = []place{
+	{"New York", "New York", "100##"},
+	{"Buffalo", "New York", "142##"},
+	{"Los Angeles", "California", "900##"},
+	{"San Diego", "California", "921##"},
+	{"San Jose", "California", "951##"},
+	{"Chicago", "Illinois", "606##"},
+	{"Houston", "Texas", "770##"},
+	{"Austin", "Texas", "787##"},
+	{"Dallas", "Texas", "752##"},
+	{"Phoenix", "Arizona", "850##"},
+	{"Philadelphia", "Pennsylvania", "191##"},
+	{"Pittsburgh", "Pennsylvania", "152##"},
+	{"Seattle", "Washington", "981##"},
+	{"Denver", "Colorado", "802##"},
+	{"Boston", "Massachusetts", "021##"},
+	{"Atlanta", "Georgia", "303##"},
+	{"Miami", "Florida", "331##"},
+	{"Orlando", "Florida", "328##"},
+	{"Portland", "Oregon", "972##"},
+	{"Nashville", "Tennessee", "372##"},
+	{"Columbus", "Ohio", "432##"},
+	{"Minneapolis", "Minnesota", "554##"},
+}
+
+// usStateNames maps the postal abbreviations of the states in usPlaces to their names
+var usStateNames = map[string]string{
+	"NY": "New York", "CA": "California", "IL": "Illinois", "TX": "Texas", "AZ": "Arizona",
+	"PA": "Pennsylvania", "WA": "Washington", "CO": "Colorado", "MA": "Massachusetts", "GA": "Georgia",
+	"FL": "Florida", "OR": "Oregon", "TN": "Tennessee", "OH": "Ohio", "MN": "Minnesota",
+}
+
+// countryLocales maps country codes and names, in lower case, to the locale
+// whose places and phone numbers are that country's. Other countries have
+// no locale, as the default one is American.
+var countryLocales = map[string]string{
+	"de": "de", "deu": "de", "germany": "de", "deutschland": "de",
+	"jp": "ja", "jpn": "ja", "japan": "ja", "日本": "ja",
+	"us": "en", "usa": "en", "united states": "en", "united states of america": "en",
+}
+
+// normalizeLocale reduces a locale such as "de_DE" or "ja-JP" to its
+// language, returning "" if that isn't a supported locale
+func normalizeLocale(name string) string {
+	lang, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(name)), "_")
+	lang, _, _ = strings.Cut(lang, "-")
+	if _, ok := locales[lang]; ok || lang == defaultLocale {
+		return lang
+	}
+	return ""
+}
+
+// localeOf reads a locale from a column value holding either a country or
+// a locale, returning "" if it names neither
+func localeOf(val any) string {
+	if val == nil {
+		return ""
+	}
+	s := strings.ToLower(strings.TrimSpace(stringValue(val)))
+	if locale, ok := countryLocales[s]; ok {
+		return locale
+	}
+	return normalizeLocale(s)
+}
+
+// checkLocale reports whether name is a supported locale
+func checkLocale(name string) error {
+	if name != "" && normalizeLocale(name) == "" {
+		return fmt.Errorf("unknown locale %q", name)
+	}
+	return nil
+}
+
+// localeFor picks the locale of a column's fake data. A column's own setting
+// wins over its table's, which wins over the global one; within each, a
+// locale read from the row wins over a fixed one.
+func localeFor(cfg *config.Config, state *rowState, rule config.ColumnRule) *localeData {
+	table := cfg.TableLocales[state.schema.Name]
+	for _, name := range []string{
+		localeOf(state.original[rule.LocaleFrom]), rule.Locale,
+		localeOf(state.original[table.From]), table.Locale,
+		cfg.Locale,
+	} {
+		if locale := normalizeLocale(name); locale != "" {
+			return locales[locale]
+		}
+	}
+	return nil
+}
+
+// numerify replaces each '#' in format with a random digit. Unlike
+// gofakeit's Numerify it leaves a leading zero alone, as trunk prefixes
+// and many postcodes start with one.
+func numerify(f *gofakeit.Faker, format string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '#' {
+			return rune('0' + f.IntN(10))
+		}
+		return r
+	}, format)
+}
+
+// firstName returns a first name for the gender, "male" or "female"
+func (l *localeData) firstName(f *gofakeit.Faker, gender string) localName {
+	if l == nil {
+		if gender == "male" {
+			return localName{text: f.RandomString(maleFirstNames)}
+		}
+		return localName{text: f.RandomString(femaleFirstNames)}
+	}
+	if gender == "male" {
+		return l.maleFirstNames[f.IntN(len(l.maleFirstNames))]
+	}
+	return l.femaleFirstNames[f.IntN(len(l.femaleFirstNames))]
+}
+
+func (l *localeData) lastName(f *gofakeit.Faker) localName {
+	if l == nil {
+		return localName{text: f.LastName()}
+	}
+	return l.lastNames[f.IntN(len(l.lastNames))]
+}
+
+// fullName joins a first and last name in the locale's order
+func (l *localeData) fullName(first, last localName) string {
+	if l != nil && l.familyNameFirst {
+		return last.text + " " + first.text
+	}
+	return first.text + " " + last.text
+}
+
+func (l *localeData) street(f *gofakeit.Faker) string {
+	if l == nil {
+		return f.Street()
+	}
+	return numerify(f, fmt.Sprintf(l.streetFormat, f.RandomString(l.streets)))
+}
+
+// place returns a city with a matching state and postcode
+func (l *localeData) place(f *gofakeit.Faker) place {
+	places := usPlaces
+	if l != nil {
+		places = l.places
+	}
+	p := places[f.IntN(len(places))]
+	p.postcode = numerify(f, p.postcode)
+	return p
+}
+
+// placeIn returns a city of the given state, if the locale knows any.
+// States match by name or, for the US, by postal abbreviation.
+func (l *localeData) placeIn(f *gofakeit.Faker, state string) (place, bool) {
+	places := usPlaces
+	if l != nil {
+		places = l.places
+	}
+	state = strings.TrimSpace(state)
+	if abbr, ok := usStateNames[strings.ToUpper(state)]; ok && l == nil {
+		state = abbr
+	}
+	var matches []place
+	for _, p := range places {
+		if strings.EqualFold(p.state, state) {
+			matches = append(matches, p)
+		}
+	}
+	if len(matches) == 0 {
+		return place{}, false
+	}
+	p := matches[f.IntN(len(matches))]
+	p.postcode = numerify(f, p.postcode)
+	return p, true
+}
+
+func (l *localeData) phone(f *gofakeit.Faker) string {
+	if l == nil {
+		return f.Phone()
+	}
+	return numerify(f, f.RandomString(l.phoneFormats))
+}
+
+// homeCountry returns the name of the locale's country
+func (l *localeData) homeCountry() string {
+	if l == nil {
+		return "United States"
+	}
+	return l.country
+}
+
+func (l *localeData) countryName(f *gofakeit.Faker) string {
+	if l == nil {
+		return f.Country()
+	}
+	return l.country
+}
+
+// latinText returns the Latin-script spelling of a name
+func (n localName) latinText() string {
+	if n.latin == "" {
+		return n.text
+	}
+	return n.latin
+}
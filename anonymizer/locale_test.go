@@ -0,0 +1,130 @@
+package anonymizer
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/andys/new_names/config"
+	"github.com/andys/new_names/db"
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/frankban/quicktest"
+)
+
+func TestLocaleOf(t *testing.T) {
+	c := quicktest.New(t)
+	c.Assert(localeOf("DE"), quicktest.Equals, "de")
+	c.Assert(localeOf(" Japan "), quicktest.Equals, "ja")
+	c.Assert(localeOf([]byte("ja_JP")), quicktest.Equals, "ja")
+	c.Assert(localeOf("United States"), quicktest.Equals, "en")
+	c.Assert(localeOf("United Kingdom"), quicktest.Equals, "")
+	c.Assert(localeOf("Austria"), quicktest.Equals, "")
+	c.Assert(localeOf("Narnia"), quicktest.Equals, "")
+	c.Assert(localeOf(nil), quicktest.Equals, "")
+}
+
+func TestNewPerson_Japanese(t *testing.T) {
+	c := quicktest.New(t)
+	f := gofakeit.New(3)
+
+	for i := 0; i < 20; i++ {
+		p := NewPerson(f, "ja-JP")
+		c.Assert(p.Name, quicktest.Equals, p.LastName+" "+p.FirstName)
+		c.Assert(utf8.RuneCountInString(p.LastName) < len(p.LastName), quicktest.IsTrue, quicktest.Commentf("%+v", p))
+		c.Assert(p.Email, quicktest.Matches, `[a-z]+\.[a-z]+\d+@example\.(com|net|org)`)
+		c.Assert(p.Postcode, quicktest.Matches, `\d{3}-\d{4}`)
+		c.Assert(p.Phone, quicktest.Matches, `0\d{1,2}-\d{4}-\d{4}`)
+		c.Assert(p.Country, quicktest.Equals, "日本")
+	}
+}
+
+func TestNewPerson_GermanEmailsAreASCII(t *testing.T) {
+	c := quicktest.New(t)
+	f := gofakeit.New(5)
+
+	for i := 0; i < 50; i++ {
+		p := NewPerson(f, "de")
+		c.Assert(p.Email, quicktest.Matches, `[a-z]+\.[a-z]+\d+@example\.(com|net|org)`)
+		c.Assert(p.Postcode, quicktest.Matches, `\d{5}`)
+	}
+}
+
+func TestAnonymize_LocaleFromRow(t *testing.T) {
+	c := quicktest.New(t)
+	schema := &db.TableSchema{
+		Name: "customers",
+		Columns: []db.ColumnSchema{
+			{Name: "id", Type: "integer"},
+			{Name: "name", Type: "varchar", MaxLength: 100},
+			{Name: "phone", Type: "varchar", MaxLength: 30},
+			{Name: "country", Type: "varchar", MaxLength: 30},
+		},
+	}
+	cfg := &config.Config{
+		AnonymizeFields: map[string][]string{"customers": {"name", "phone"}},
+		TableLocales:    map[string]config.LocaleRule{"customers": {Locale: "de", From: "country"}},
+	}
+
+	japanese := regexp.MustCompile(`^\p{Han}+ \p{Han}+|\p{Hiragana}+$`)
+	for i := 0; i < 10; i++ {
+		row := &Row{Schema: schema, Data: map[string]interface{}{"id": 1, "name": "Bob Smith", "phone": "555-1234", "country": "JP"}}
+		c.Assert(Anonymize(row, cfg), quicktest.IsNil)
+		c.Assert(japanese.MatchString(row.Data["name"].(string)), quicktest.IsTrue, quicktest.Commentf("%v", row.Data))
+		c.Assert(row.Data["phone"], quicktest.Matches, `0\d{1,2}-\d{4}-\d{4}`)
+
+		// Countries without a locale of their own fall back to the table's
+		row = &Row{Schema: schema, Data: map[string]interface{}{"id": 2, "name": "Bob Smith", "phone": "555-1234", "country": "Narnia"}}
+		c.Assert(Anonymize(row, cfg), quicktest.IsNil)
+		name := strings.Fields(row.Data["name"].(string))
+		var found bool
+		for _, last := range locales["de"].lastNames {
+			found = found || last.text == name[len(name)-1]
+		}
+		c.Assert(found, quicktest.IsTrue, quicktest.Commentf("%v", row.Data))
+	}
+}
+
+func TestAnonymize_TruncatesMultibyteByCharacter(t *testing.T) {
+	c := quicktest.New(t)
+	schema := &db.TableSchema{
+		Name:    "customers",
+		Columns: []db.ColumnSchema{{Name: "city", Type: "varchar", MaxLength: 2}},
+	}
+	cfg := &config.Config{
+		Locale:          "ja",
+		AnonymizeFields: map[string][]string{"customers": {"city"}},
+		ColumnRules:     map[string]map[string]config.ColumnRule{"customers": {"city": {Strategy: "city"}}},
+	}
+
+	for i := 0; i < 20; i++ {
+		row := &Row{Schema: schema, Data: map[string]interface{}{"city": "Springfield"}}
+		c.Assert(Anonymize(row, cfg), quicktest.IsNil)
+		city := row.Data["city"].(string)
+		c.Assert(utf8.ValidString(city), quicktest.IsTrue)
+		c.Assert(utf8.RuneCountInString(city), quicktest.Equals, 2)
+	}
+}
+
+func TestValidate_Locales(t *testing.T) {
+	c := quicktest.New(t)
+	schemas := []db.TableSchema{{
+		Name:    "customers",
+		Columns: []db.ColumnSchema{{Name: "name", Type: "varchar"}, {Name: "country", Type: "varchar"}},
+	}}
+
+	cfg := &config.Config{Locale: "xx"}
+	c.Assert(Validate(cfg, schemas), quicktest.ErrorMatches, `unknown locale "xx"`)
+
+	cfg = &config.Config{TableLocales: map[string]config.LocaleRule{"customers": {From: "nation"}}}
+	c.Assert(Validate(cfg, schemas), quicktest.ErrorMatches, `table customers: locale column nation does not exist`)
+
+	cfg = &config.Config{ColumnRules: map[string]map[string]config.ColumnRule{"customers": {"name": {Locale: "klingon"}}}}
+	c.Assert(Validate(cfg, schemas), quicktest.ErrorMatches, `column customers.name: unknown locale "klingon"`)
+
+	cfg = &config.Config{
+		Locale:      "de_DE",
+		ColumnRules: map[string]map[string]config.ColumnRule{"customers": {"name": {Strategy: "name", LocaleFrom: "country"}}},
+	}
+	c.Assert(Validate(cfg, schemas), quicktest.IsNil)
+}
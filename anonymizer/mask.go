@@ -0,0 +1,45 @@
+package anonymizer
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// MaskOptions controls which parts of a value MaskFormat leaves untouched
+type MaskOptions struct {
+	KeepPrefix int  // Number of leading characters to keep
+	KeepSuffix int  // Number of trailing characters to keep
+	KeepDomain bool // Keep an email's domain, from the last '@' onwards
+}
+
+// MaskFormat replaces every letter with a random letter of the same case and
+// every digit with a random digit. Punctuation and whitespace stay where they
+// are, so "AB-1234-X" becomes something like "QF-8071-K" and a phone number
+// keeps its grouping.
+func MaskFormat(f *gofakeit.Faker, s string, opts MaskOptions) string {
+	runes := []rune(s)
+	end := len(runes) - opts.KeepSuffix
+	if opts.KeepDomain {
+		if at := strings.LastIndex(s, "@"); at >= 0 {
+			end = min(end, len([]rune(s[:at])))
+		}
+	}
+	// Counts beyond the value's length, or below zero, keep all or nothing
+	start := max(0, min(opts.KeepPrefix, len(runes)))
+	end = max(0, min(end, len(runes)))
+
+	for i := start; i < end; i++ {
+		r := runes[i]
+		switch {
+		case unicode.IsDigit(r):
+			runes[i] = rune('0' + f.IntN(10))
+		case unicode.IsUpper(r):
+			runes[i] = rune('A' + f.IntN(26))
+		case unicode.IsLetter(r):
+			runes[i] = rune('a' + f.IntN(26))
+		}
+	}
+	return string(runes)
+}
@@ -0,0 +1,43 @@
+package anonymizer
+
+import (
+	"testing"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/frankban/quicktest"
+)
+
+func TestMaskFormat_PreservesCharacterClasses(t *testing.T) {
+	c := quicktest.New(t)
+	f := gofakeit.New(42)
+
+	masked := MaskFormat(f, "AB-1234-x", MaskOptions{})
+	c.Assert(masked, quicktest.Matches, `[A-Z]{2}-[0-9]{4}-[a-z]`)
+	c.Assert(masked, quicktest.Not(quicktest.Equals), "AB-1234-x")
+}
+
+func TestMaskFormat_KeepsPrefixAndSuffix(t *testing.T) {
+	c := quicktest.New(t)
+	f := gofakeit.New(42)
+
+	masked := MaskFormat(f, "+44 20 7946 0958", MaskOptions{KeepPrefix: 3, KeepSuffix: 2})
+	c.Assert(masked, quicktest.Matches, `\+44 \d{2} \d{4} \d{2}58`)
+}
+
+func TestMaskFormat_ClampsKeptCounts(t *testing.T) {
+	c := quicktest.New(t)
+	f := gofakeit.New(42)
+
+	c.Assert(MaskFormat(f, "AB-12", MaskOptions{KeepSuffix: -2}), quicktest.Matches, `[A-Z]{2}-\d{2}`)
+	c.Assert(MaskFormat(f, "AB-12", MaskOptions{KeepPrefix: -1}), quicktest.Matches, `[A-Z]{2}-\d{2}`)
+	c.Assert(MaskFormat(f, "AB-12", MaskOptions{KeepPrefix: 9}), quicktest.Equals, "AB-12")
+	c.Assert(MaskFormat(f, "AB-12", MaskOptions{KeepSuffix: 9}), quicktest.Equals, "AB-12")
+}
+
+func TestMaskFormat_KeepsEmailDomain(t *testing.T) {
+	c := quicktest.New(t)
+	f := gofakeit.New(42)
+
+	masked := MaskFormat(f, "John.Smith@corp.example.com", MaskOptions{KeepDomain: true})
+	c.Assert(masked, quicktest.Matches, `[A-Z][a-z]{3}\.[A-Z][a-z]{4}@corp\.example\.com`)
+}
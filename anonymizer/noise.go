@@ -0,0 +1,91 @@
+package anonymizer
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/andys/new_names/db"
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// defaultNoisePercent is the noise range used when percent isn't set
+const defaultNoisePercent = 10
+
+// Perturb multiplies n by a random factor within ±percent, rounds the result
+// to scale decimal places (no rounding if scale is negative) and clamps it to
+// lo and hi when those are set
+func Perturb(f *gofakeit.Faker, n, percent float64, scale int, lo, hi *float64) float64 {
+	v := n * (1 + f.Float64Range(-percent, percent)/100)
+	if scale >= 0 {
+		pow := math.Pow(10, float64(scale))
+		v = math.Round(v*pow) / pow
+	}
+	if lo != nil && v < *lo {
+		v = *lo
+	}
+	if hi != nil && v > *hi {
+		v = *hi
+	}
+	return v
+}
+
+// numericValue converts a scanned numeric value to float64. It also returns
+// the number of decimal places of textual values, which is how MySQL and
+// PostgreSQL drivers return DECIMAL/NUMERIC columns, or -1 for other types.
+func numericValue(val any) (float64, int, error) {
+	switch v := val.(type) {
+	case int, int8, int16, int32, int64:
+		return float64(toInt64(v)), 0, nil
+	case uint, uint8, uint16, uint32, uint64:
+		return float64(toUint64(v)), 0, nil
+	case float32:
+		return float64(v), -1, nil
+	case float64:
+		return v, -1, nil
+	}
+	s := strings.TrimSpace(stringValue(val))
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, 0, badValue(fmt.Errorf("cannot parse %q as a number", s))
+	}
+	places := 0
+	if dot := strings.IndexByte(s, '.'); dot >= 0 {
+		places = len(s) - dot - 1
+	}
+	return n, places, nil
+}
+
+// perturbValue applies the noise rule to a column value, keeping its type:
+// integer columns stay whole numbers and decimal text keeps its scale
+func perturbValue(f *gofakeit.Faker, in *Input) (any, error) {
+	n, places, err := numericValue(in.Value)
+	if err != nil {
+		return nil, err
+	}
+	isInt := logicalType(in.Column) == db.TypeInteger
+	scale := places
+	switch {
+	case isInt:
+		scale = 0
+	case in.Rule.Scale != nil:
+		scale = *in.Rule.Scale
+	}
+	percent := in.Rule.Percent
+	if percent == 0 {
+		percent = defaultNoisePercent
+	}
+
+	v := Perturb(f, n, percent, scale, in.Rule.Min, in.Rule.Max)
+	switch in.Value.(type) {
+	case float32, float64:
+		return v, nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return int64(math.Round(v)), nil
+	}
+	if isInt {
+		return strconv.FormatInt(int64(math.Round(v)), 10), nil
+	}
+	return strconv.FormatFloat(v, 'f', scale, 64), nil
+}
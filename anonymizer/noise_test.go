@@ -0,0 +1,71 @@
+package anonymizer
+
+import (
+	"testing"
+
+	"github.com/andys/new_names/config"
+	"github.com/andys/new_names/db"
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/frankban/quicktest"
+)
+
+func TestPerturb_StaysWithinRange(t *testing.T) {
+	c := quicktest.New(t)
+	f := gofakeit.New(42)
+
+	for i := 0; i < 100; i++ {
+		v := Perturb(f, 1000, 5, 2, nil, nil)
+		c.Assert(v >= 950 && v <= 1050, quicktest.IsTrue, quicktest.Commentf("got %v", v))
+	}
+}
+
+func TestPerturb_Clamps(t *testing.T) {
+	c := quicktest.New(t)
+	f := gofakeit.New(42)
+	lo, hi := 40.0, 41.0
+
+	for i := 0; i < 20; i++ {
+		v := Perturb(f, 40.5, 50, 1, &lo, &hi)
+		c.Assert(v >= lo && v <= hi, quicktest.IsTrue, quicktest.Commentf("got %v", v))
+	}
+}
+
+func TestPerturbValue_KeepsTypes(t *testing.T) {
+	c := quicktest.New(t)
+	f := gofakeit.New(42)
+
+	got, err := perturbValue(f, &Input{Value: int64(52000), Column: colOfType("int")})
+	c.Assert(err, quicktest.IsNil)
+	_, ok := got.(int64)
+	c.Assert(ok, quicktest.IsTrue)
+
+	got, err = perturbValue(f, &Input{Value: []byte("123.45"), Column: colOfType("decimal")})
+	c.Assert(err, quicktest.IsNil)
+	c.Assert(got, quicktest.Matches, `1\d\d\.\d\d`)
+
+	got, err = perturbValue(f, &Input{Value: 72.5, Column: colOfType("double")})
+	c.Assert(err, quicktest.IsNil)
+	_, ok = got.(float64)
+	c.Assert(ok, quicktest.IsTrue)
+
+	_, err = perturbValue(f, &Input{Value: "n/a", Column: colOfType("decimal")})
+	c.Assert(err, quicktest.ErrorMatches, `cannot parse "n/a" as a number`)
+	c.Assert(err, quicktest.ErrorIs, ErrBadValue)
+}
+
+func colOfType(t string) db.ColumnSchema {
+	return db.ColumnSchema{Name: "n", Type: t}
+}
+
+func TestPerturbValue_NonIntegerTypesNamedLikeInt(t *testing.T) {
+	c := quicktest.New(t)
+	// "interval" and "point" contain "int" but don't hold whole numbers
+	in := &Input{Value: 10.25, Column: db.ColumnSchema{Type: "interval"}, Rule: config.ColumnRule{Percent: 50}}
+	seen := false
+	for i := 0; i < 20 && !seen; i++ {
+		v, err := perturbValue(gofakeit.New(uint64(i+1)), in)
+		c.Assert(err, quicktest.IsNil)
+		seen = v.(float64) != float64(int64(v.(float64)))
+	}
+	c.Assert(seen, quicktest.IsTrue)
+}
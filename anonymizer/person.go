@@ -0,0 +1,119 @@
+package anonymizer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// gofakeit's first names carry no gender, so a person's first name is drawn
+// from one of these lists to match their gender
+var (
+	femaleFirstNames = []string{
+		"Alice", "Amelia", "Anna", "Charlotte", "Chloe", "Claire", "Olivia", "Emily", "Emma", "Grace",
+		"Hannah", "Isabella", "Julia", "Laura", "Lily", "Lucy", "Maria", "Mia", "Natalie", "Nora",
+		"Rachel", "Rebecca", "Sarah", "Sophia", "Victoria", "Zoe",
+	}
+	maleFirstNames = []string{
+		"Adam", "Alexander", "Andrew", "Benjamin", "Charles", "Daniel", "David", "Edward", "Ethan", "George",
+		"Henry", "Jack", "James", "John", "Joseph", "Liam", "Lucas", "Matthew", "Michael", "Noah",
+		"Oliver", "Peter", "Robert", "Samuel", "Thomas", "William",
+	}
+)
+
+// personDomains are reserved example domains, so generated emails can never reach a real mailbox
+var personDomains = []string{"example.com", "example.net", "example.org"}
+
+// Person is a fake identity whose fields are consistent with each other
+type Person struct {
+	FirstName string
+	LastName  string
+	Name      string // Full name, in the order usual for the locale
+	Gender    string // "male" or "female"
+	Email     string
+	Username  string
+	Phone     string
+	Street    string
+	City      string
+	State     string
+	Postcode  string
+	Country   string
+	Birthdate time.Time
+}
+
+// NewPerson generates a person whose name, gender, email and username
+// agree, with names, address and phone number in the given locale. An empty
+// or unknown locale gives an American person.
+func NewPerson(f *gofakeit.Faker, locale string) *Person {
+	return newPerson(f, locales[normalizeLocale(locale)])
+}
+
+func newPerson(f *gofakeit.Faker, l *localeData) *Person {
+	p := &Person{
+		Gender: f.RandomString([]string{"male", "female"}),
+		Phone:  l.phone(f),
+		Street: l.street(f),
+	}
+	first, last := l.firstName(f, p.Gender), l.lastName(f)
+	p.FirstName, p.LastName, p.Name = first.text, last.text, l.fullName(first, last)
+	place := l.place(f)
+	p.City, p.State, p.Postcode, p.Country = place.city, place.state, place.postcode, l.homeCountry()
+
+	firstSlug, lastSlug := templateFilters["slug"](first.latinText()), templateFilters["slug"](last.latinText())
+	p.Email = fmt.Sprintf("%s.%s%d@%s", firstSlug, lastSlug, f.IntRange(1, 99), f.RandomString(personDomains))
+	p.Username = fmt.Sprintf("%s%s%d", firstSlug, lastSlug[:min(len(lastSlug), 1)], f.IntRange(10, 9999))
+
+	p.Birthdate = f.DateRange(birthdateRangeStart, birthdateRangeEnd).UTC().Truncate(24 * time.Hour)
+	return p
+}
+
+// birthdateRangeStart and birthdateRangeEnd bound generated birthdates to
+// adults of 18 to 90 in 2024. Like dateRangeStart, they are fixed rather
+// than relative to today, so keyed runs don't churn from one day to the next.
+var (
+	birthdateRangeStart = time.Date(1934, 1, 1, 0, 0, 0, 0, time.UTC)
+	birthdateRangeEnd   = time.Date(2006, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// personFields maps the field names usable in config to the person's values
+var personFields = map[string]func(p *Person) any{
+	"first_name":     func(p *Person) any { return p.FirstName },
+	"last_name":      func(p *Person) any { return p.LastName },
+	"name":           func(p *Person) any { return p.Name },
+	"gender":         func(p *Person) any { return p.Gender },
+	"email":          func(p *Person) any { return p.Email },
+	"username":       func(p *Person) any { return p.Username },
+	"phone":          func(p *Person) any { return p.Phone },
+	"street_address": func(p *Person) any { return p.Street },
+	"city":           func(p *Person) any { return p.City },
+	"state":          func(p *Person) any { return p.State },
+	"postcode":       func(p *Person) any { return p.Postcode },
+	"country":        func(p *Person) any { return p.Country },
+	"birthdate":      func(p *Person) any { return p.Birthdate },
+}
+
+// personValue returns a person field in the form of the value it replaces:
+// genders follow the original's style ("F", "Female" or "female") and
+// birthdates stay time.Time only if the original was one
+func personValue(p *Person, field string, original any) any {
+	v := personFields[field](p)
+	switch field {
+	case "gender":
+		orig := stringValue(original)
+		switch {
+		case len(orig) == 1:
+			return strings.ToUpper(p.Gender[:1])
+		case orig != "" && orig == strings.ToUpper(orig):
+			return strings.ToUpper(p.Gender)
+		case orig != "" && orig[:1] == strings.ToUpper(orig[:1]):
+			return strings.ToUpper(p.Gender[:1]) + p.Gender[1:]
+		}
+	case "birthdate":
+		if _, ok := original.(time.Time); !ok {
+			return p.Birthdate.Format("2006-01-02")
+		}
+	}
+	return v
+}
@@ -0,0 +1,40 @@
+package anonymizer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/frankban/quicktest"
+)
+
+func TestNewPerson_IsConsistent(t *testing.T) {
+	c := quicktest.New(t)
+	f := gofakeit.New(7)
+
+	for i := 0; i < 20; i++ {
+		p := NewPerson(f, "")
+		first := strings.ToLower(p.FirstName)
+		c.Assert(strings.HasPrefix(p.Email, first+"."+strings.ToLower(p.LastName)), quicktest.IsTrue, quicktest.Commentf("%+v", p))
+		c.Assert(strings.HasPrefix(p.Username, first), quicktest.IsTrue)
+		if p.Gender == "male" {
+			c.Assert(maleFirstNames, quicktest.Contains, p.FirstName)
+		} else {
+			c.Assert(femaleFirstNames, quicktest.Contains, p.FirstName)
+		}
+		c.Assert(p.Birthdate.Before(birthdateRangeStart) || p.Birthdate.After(birthdateRangeEnd), quicktest.IsFalse, quicktest.Commentf("%v", p.Birthdate))
+	}
+}
+
+func TestPersonValue_FollowsOriginalStyle(t *testing.T) {
+	c := quicktest.New(t)
+	p := &Person{Gender: "female", Birthdate: time.Date(1980, 6, 15, 0, 0, 0, 0, time.UTC)}
+
+	c.Assert(personValue(p, "gender", "M"), quicktest.Equals, "F")
+	c.Assert(personValue(p, "gender", "Male"), quicktest.Equals, "Female")
+	c.Assert(personValue(p, "gender", []byte("MALE")), quicktest.Equals, "FEMALE")
+	c.Assert(personValue(p, "gender", "male"), quicktest.Equals, "female")
+	c.Assert(personValue(p, "birthdate", []byte("1971-01-01")), quicktest.Equals, "1980-06-15")
+	c.Assert(personValue(p, "birthdate", time.Now()), quicktest.Equals, p.Birthdate)
+}
@@ -0,0 +1,72 @@
+package anonymizer
+
+import (
+	"slices"
+
+	"github.com/andys/new_names/config"
+	"github.com/andys/new_names/db"
+)
+
+// Unrepeatable lists what a run with the same seed wouldn't generate again,
+// as table.column for columns and table for whole tables. Without a key,
+// date_shift, noise, shuffle, scrub tokens and deterministic dictionary
+// picks come from a secret that lasts one run, and a table with no ID whose
+// every column is anonymized has nothing public to seed its rows from. With
+// a key, everything repeats and it lists nothing.
+func Unrepeatable(cfg *config.Config, schemas []db.TableSchema) []string {
+	if cfg.Key != "" {
+		return nil
+	}
+	var out []string
+	for _, schema := range schemas {
+		fields := cfg.AnonymizeFields[schema.Name]
+		if len(fields) == 0 || slices.Contains(cfg.SkipTables, schema.Name) {
+			continue
+		}
+		state := &rowState{schema: &schema}
+		if _, ok := state.placeKey(cfg); !ok {
+			out = append(out, schema.Name)
+			continue
+		}
+		for _, col := range schema.Columns {
+			if slices.Contains(fields, col.Name) && usesRunSecret(cfg, col, cfg.Rule(schema.Name, col.Name)) {
+				out = append(out, schema.Name+"."+col.Name)
+			}
+		}
+	}
+	return out
+}
+
+// usesRunSecret reports whether a rule draws on the key, or without one on
+// the run's secret, rather than the seed. Rules of JSON paths, scrub
+// patterns and array elements count too.
+func usesRunSecret(cfg *config.Config, col db.ColumnSchema, rule config.ColumnRule) bool {
+	kind := rule.Strategy
+	if kind == "" {
+		kind = guessKind(col)
+	}
+	switch kind {
+	case "date_shift", "noise", "shuffle":
+		return true
+	case "dictionary":
+		return rule.Deterministic
+	case "scrub":
+		if rule.Tokens {
+			return true
+		}
+		for _, name := range scrubPatternNames(rule) {
+			if p, err := lookupScrubPattern(cfg, name); err == nil && usesRunSecret(cfg, scrubColumn(col, name), p.rule) {
+				return true
+			}
+		}
+	case "json":
+		for path, leafRule := range rule.Paths {
+			if usesRunSecret(cfg, leafColumn(col, path), leafRule) {
+				return true
+			}
+		}
+	case "array":
+		return usesRunSecret(cfg, elementColumn(col), config.ColumnRule{})
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package anonymizer
+
+import (
+	"testing"
+
+	"github.com/andys/new_names/config"
+	"github.com/andys/new_names/db"
+	"github.com/frankban/quicktest"
+)
+
+func TestUnrepeatable(t *testing.T) {
+	c := quicktest.New(t)
+	schemas := []db.TableSchema{
+		{Name: "users", HasID: true, IDCol: "id", Columns: []db.ColumnSchema{
+			{Name: "id", Type: "integer", IsID: true},
+			{Name: "email", Type: "varchar"},
+			{Name: "signed_up", Type: "date"},
+			{Name: "notes", Type: "text"},
+			{Name: "bio", Type: "text"},
+			{Name: "profile", Type: "jsonb"},
+			{Name: "scores", Type: "ARRAY", ElementType: "int4"},
+			{Name: "holidays", Type: "ARRAY", ElementType: "date"},
+		}},
+		{Name: "events", Columns: []db.ColumnSchema{{Name: "kind", Type: "varchar"}, {Name: "payload", Type: "text"}}},
+		{Name: "logs", Columns: []db.ColumnSchema{{Name: "line", Type: "text"}}},
+	}
+	cfg := &config.Config{
+		AnonymizeFields: map[string][]string{
+			"users":  {"email", "signed_up", "notes", "bio", "profile", "scores", "holidays"},
+			"events": {"kind", "payload"},
+			"logs":   {"line"},
+		},
+		ColumnRules: map[string]map[string]config.ColumnRule{"users": {
+			"notes":   {Strategy: "scrub", Tokens: true},
+			"bio":     {Strategy: "scrub"},
+			"profile": {Strategy: "json", Paths: map[string]config.ColumnRule{"$.born": {Strategy: "date_shift"}}},
+		}},
+		SkipTables: []string{"logs"},
+	}
+
+	c.Assert(Unrepeatable(cfg, schemas), quicktest.DeepEquals, []string{
+		"users.signed_up", "users.notes", "users.profile", "users.holidays", "events",
+	})
+
+	cfg.Key = "secret"
+	c.Assert(Unrepeatable(cfg, schemas), quicktest.HasLen, 0)
+}
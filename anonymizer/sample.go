@@ -0,0 +1,13 @@
+package anonymizer
+
+import "github.com/andys/new_names/config"
+
+// Sampled reports whether a row falls in the sample of percent rows kept
+// from its table. It is decided per row rather than by position, as rows
+// of tables without an ID column come back in no particular order: by the
+// seed and what the destination shows of the row, by the key and the row's
+// values, or at random with neither. Call it before Anonymize.
+func Sampled(row *Row, cfg *config.Config, percent float64) bool {
+	state := &rowState{schema: row.Schema, original: row.Data}
+	return rowFaker(cfg, state, "sample", "", state.rowKey()).Float64()*100 < percent
+}
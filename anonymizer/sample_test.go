@@ -0,0 +1,38 @@
+package anonymizer
+
+import (
+	"testing"
+
+	"github.com/andys/new_names/config"
+	"github.com/andys/new_names/db"
+	"github.com/frankban/quicktest"
+)
+
+func TestSampled_FollowsSeedNotOrder(t *testing.T) {
+	c := quicktest.New(t)
+	schema := &db.TableSchema{Name: "events", Columns: []db.ColumnSchema{
+		{Name: "kind", Type: "varchar"}, {Name: "payload", Type: "text"},
+	}}
+	seed := uint64(7)
+	cfg := &config.Config{Seed: &seed, AnonymizeFields: map[string][]string{"events": {"payload"}}}
+
+	sample := func(order []int) map[int]bool {
+		picked := make(map[int]bool)
+		for _, i := range order {
+			row := &Row{Schema: schema, Data: map[string]any{"kind": string(rune('a'+i%26)) + string(rune('a'+i/26)), "payload": "secret"}}
+			if Sampled(row, cfg, 25) {
+				picked[i] = true
+			}
+		}
+		return picked
+	}
+	forward := make([]int, 400)
+	backward := make([]int, 400)
+	for i := range forward {
+		forward[i], backward[i] = i, 399-i
+	}
+
+	picked := sample(forward)
+	c.Assert(sample(backward), quicktest.DeepEquals, picked)
+	c.Assert(len(picked) > 60 && len(picked) < 140, quicktest.IsTrue, quicktest.Commentf("picked %d of 400", len(picked)))
+}
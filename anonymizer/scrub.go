@@ -0,0 +1,188 @@
+package anonymizer
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/andys/new_names/config"
+	"github.com/andys/new_names/db"
+)
+
+// scrubPattern finds one kind of PII in free text
+type scrubPattern struct {
+	re    *regexp.Regexp
+	rule  config.ColumnRule // How matches are replaced
+	valid func(string) bool // Optional check to weed out false positives
+}
+
+// builtinScrubPatterns are the patterns available without configuration
+var builtinScrubPatterns = map[string]scrubPattern{
+	"email": {
+		re:   regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+		rule: config.ColumnRule{Strategy: "email"},
+	},
+	"phone": {
+		re:   regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{2,4}\)[ .-]?)?\d{3,4}[ .-]\d{3,4}(?:[ .-]\d{2,4})?`),
+		rule: config.ColumnRule{Strategy: "mask"},
+	},
+	"credit_card": {
+		re:    regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+		rule:  config.ColumnRule{Strategy: "credit_card"},
+		valid: luhnValid,
+	},
+	"iban": {
+		re:    regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,3})?\b`),
+		rule:  config.ColumnRule{Strategy: "iban"},
+		valid: ibanValid,
+	},
+	"ssn": {
+		re:   regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+		rule: config.ColumnRule{Strategy: "ssn"},
+	},
+	"nino": {
+		re:   regexp.MustCompile(`\b[A-CEGHJ-PR-TW-Z][A-CEGHJ-NPR-TW-Z] ?\d{2} ?\d{2} ?\d{2} ?[A-D]\b`),
+		rule: config.ColumnRule{Strategy: "nino"},
+	},
+}
+
+// defaultScrubPatterns are applied when a column doesn't list any. Card
+// numbers and identifiers go first so their digit groups aren't taken for phones.
+var defaultScrubPatterns = []string{"credit_card", "iban", "ssn", "nino", "email", "phone"}
+
+// luhnValid reports whether the digits in s pass the Luhn checksum
+func luhnValid(s string) bool {
+	sum, double, digits := 0, false, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+		digits++
+	}
+	return digits > 0 && sum%10 == 0
+}
+
+// compiledRegexps caches configured regular expressions by source
+var compiledRegexps sync.Map
+
+// compileCached compiles expr, re-using an earlier compilation when there is one
+func compileCached(expr string) (*regexp.Regexp, error) {
+	if re, ok := compiledRegexps.Load(expr); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	compiledRegexps.Store(expr, re)
+	return re, nil
+}
+
+// lookupScrubPattern finds a named pattern, preferring configured patterns
+// so they can override the built-in ones
+func lookupScrubPattern(cfg *config.Config, name string) (scrubPattern, error) {
+	if custom, ok := cfg.ScrubPatterns[name]; ok {
+		re, err := compileCached(custom.Regex)
+		if err != nil {
+			return scrubPattern{}, fmt.Errorf("scrub pattern %s: %w", name, err)
+		}
+		strategy := custom.Strategy
+		if strategy == "" {
+			strategy = "mask"
+		}
+		return scrubPattern{re: re, rule: config.ColumnRule{Strategy: strategy}}, nil
+	}
+	if builtin, ok := builtinScrubPatterns[name]; ok {
+		return builtin, nil
+	}
+	return scrubPattern{}, fmt.Errorf("unknown scrub pattern %q", name)
+}
+
+// scrubPatternNames returns the patterns a scrub rule applies, in order
+func scrubPatternNames(rule config.ColumnRule) []string {
+	if len(rule.Patterns) > 0 {
+		return rule.Patterns
+	}
+	return defaultScrubPatterns
+}
+
+// scrubColumn describes the matches of a pattern as a column, so they can be anonymized like one
+func scrubColumn(col db.ColumnSchema, name string) db.ColumnSchema {
+	return db.ColumnSchema{Name: col.Name + "[" + name + "]", Nullable: true}
+}
+
+// scrubMatch is a stretch of text claimed by one pattern
+type scrubMatch struct {
+	start, end int
+	name       string
+	pattern    scrubPattern
+}
+
+// findScrubMatches finds the PII in text. Patterns are tried in order and
+// earlier patterns win, so a card number isn't later re-read as a phone.
+func findScrubMatches(cfg *config.Config, rule config.ColumnRule, text string) ([]scrubMatch, error) {
+	var matches []scrubMatch
+	for _, name := range scrubPatternNames(rule) {
+		pattern, err := lookupScrubPattern(cfg, name)
+		if err != nil {
+			return nil, err
+		}
+	search:
+		for _, loc := range pattern.re.FindAllStringIndex(text, -1) {
+			if pattern.valid != nil && !pattern.valid(text[loc[0]:loc[1]]) {
+				continue
+			}
+			for _, m := range matches {
+				if loc[0] < m.end && m.start < loc[1] {
+					continue search
+				}
+			}
+			matches = append(matches, scrubMatch{start: loc[0], end: loc[1], name: name, pattern: pattern})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].start < matches[j].start })
+	return matches, nil
+}
+
+// scrubText replaces the PII found in a text value, leaving the surrounding
+// prose untouched. Matches become fakes of the same kind, or tokens such as
+// [EMAIL-3f2a9c1d] that are the same wherever the same value appears.
+func scrubText(in *Input) (any, error) {
+	text := stringValue(in.Value)
+	matches, err := findScrubMatches(in.Config, in.Rule, text)
+	if err != nil {
+		return nil, err
+	}
+
+	var out strings.Builder
+	last := 0
+	for _, m := range matches {
+		match := text[m.start:m.end]
+		out.WriteString(text[last:m.start])
+		last = m.end
+		if in.Rule.Tokens {
+			token := HashValue(string(secretKey(in.Config)), m.name+"\x00"+match, "hex")
+			out.WriteString("[" + strings.ToUpper(m.name) + "-" + token[:8] + "]")
+			continue
+		}
+		fake, err := transform(in.Config, in.state, in.Row, scrubColumn(in.Column, m.name), m.pattern.rule, match)
+		if err != nil {
+			return nil, fmt.Errorf("scrub pattern %s: %w", m.name, err)
+		}
+		out.WriteString(stringValue(fake))
+	}
+	out.WriteString(text[last:])
+	return out.String(), nil
+}
@@ -0,0 +1,76 @@
+package anonymizer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/andys/new_names/config"
+	"github.com/andys/new_names/db"
+	"github.com/frankban/quicktest"
+)
+
+var ticketSchema = &db.TableSchema{
+	Name:    "support_tickets",
+	Columns: []db.ColumnSchema{{Name: "body", Type: "text"}},
+}
+
+func scrubConfig(rule config.ColumnRule) *config.Config {
+	return &config.Config{
+		AnonymizeFields: map[string][]string{"support_tickets": {"body"}},
+		ColumnRules:     map[string]map[string]config.ColumnRule{"support_tickets": {"body": rule}},
+	}
+}
+
+func TestLuhnValid(t *testing.T) {
+	c := quicktest.New(t)
+	c.Assert(luhnValid("4111 1111 1111 1111"), quicktest.IsTrue)
+	c.Assert(luhnValid("4111-1111-1111-1112"), quicktest.IsFalse)
+}
+
+func TestAnonymize_ScrubReplacesOnlyPII(t *testing.T) {
+	c := quicktest.New(t)
+	body := "Hi, I'm jane@corp.com, call me on +44 20 7946 0958. " +
+		"Card 4111 1111 1111 1111 was charged twice, refund to GB82 WEST 1234 5698 7654 32. Order 2024-01-02."
+	row := &Row{Schema: ticketSchema, Data: map[string]interface{}{"body": body}}
+
+	c.Assert(Anonymize(row, scrubConfig(config.ColumnRule{Strategy: "scrub"})), quicktest.IsNil)
+
+	out := row.Data["body"].(string)
+	c.Assert(out, quicktest.Matches, `Hi, I'm \S+@\S+, call me on \+\d\d \d\d \d{4} \d{4}\. `+
+		`Card 4\d{3} \d{4} \d{4} \d{4} was charged twice, refund to GB\d\d [A-Z]{4} \d{4} \d{4} \d{4} \d\d\. Order 2024-01-02\.`)
+	for _, pii := range []string{"jane@corp.com", "7946 0958", "4111 1111 1111 1111", "WEST 1234"} {
+		c.Assert(strings.Contains(out, pii), quicktest.IsFalse, quicktest.Commentf("%s still in %q", pii, out))
+	}
+}
+
+func TestAnonymize_ScrubTokensAndCustomPatterns(t *testing.T) {
+	c := quicktest.New(t)
+	cfg := scrubConfig(config.ColumnRule{Strategy: "scrub", Patterns: []string{"email", "employee_id"}, Tokens: true})
+	cfg.Key = "s3cret"
+	cfg.ScrubPatterns = map[string]config.ScrubPattern{
+		"employee_id": {Regex: `EMP-\d{6}`},
+	}
+
+	first := &Row{Schema: ticketSchema, Data: map[string]interface{}{"body": "From jane@corp.com (EMP-123456)"}}
+	second := &Row{Schema: ticketSchema, Data: map[string]interface{}{"body": "cc jane@corp.com"}}
+	c.Assert(Anonymize(first, cfg), quicktest.IsNil)
+	c.Assert(Anonymize(second, cfg), quicktest.IsNil)
+
+	c.Assert(first.Data["body"], quicktest.Matches, `From (\[EMAIL-[0-9a-f]{8}\]) \(\[EMPLOYEE_ID-[0-9a-f]{8}\]\)`)
+	token := strings.Fields(first.Data["body"].(string))[1]
+	c.Assert(second.Data["body"], quicktest.Equals, "cc "+token)
+}
+
+func TestValidate_ScrubPatterns(t *testing.T) {
+	c := quicktest.New(t)
+	schemas := []db.TableSchema{*ticketSchema}
+
+	cfg := scrubConfig(config.ColumnRule{Strategy: "scrub", Patterns: []string{"passport"}})
+	c.Assert(Validate(cfg, schemas), quicktest.ErrorMatches, `column support_tickets.body: unknown scrub pattern "passport"`)
+
+	cfg.ScrubPatterns = map[string]config.ScrubPattern{"passport": {Regex: `[A-Z`}}
+	c.Assert(Validate(cfg, schemas), quicktest.ErrorMatches, `column support_tickets.body: scrub pattern passport: .*`)
+
+	cfg.ScrubPatterns = map[string]config.ScrubPattern{"passport": {Regex: `[A-Z]\d{8}`, Strategy: "nope"}}
+	c.Assert(Validate(cfg, schemas), quicktest.ErrorMatches, `column support_tickets.body\[passport\]: unknown anonymization strategy "nope"`)
+}
@@ -0,0 +1,206 @@
+package anonymizer
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/andys/new_names/config"
+	"github.com/andys/new_names/db"
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// ShuffleDeck holds the values of a table's shuffled columns. The reader
+// fills it in a first pass over the table; rows then draw their values from
+// it without replacement, so each column keeps exactly its original values
+// while their link to individual rows is broken.
+type ShuffleDeck struct {
+	mu     sync.Mutex
+	cfg    *config.Config
+	schema *db.TableSchema
+	rules  map[string]config.ColumnRule // Shuffled column to its rule
+	values map[string][]any             // Column and partition to the values collected
+	piles  map[string]map[string][]any  // Column and partition to the values not dealt yet, by the original they go to
+}
+
+// NewShuffleDeck returns an empty deck for the table, or nil if none of
+// its anonymized columns use the shuffle strategy
+func NewShuffleDeck(schema *db.TableSchema, cfg *config.Config) *ShuffleDeck {
+	rules := make(map[string]config.ColumnRule)
+	for _, column := range cfg.AnonymizeFields[schema.Name] {
+		if rule := cfg.Rule(schema.Name, column); rule.Strategy == "shuffle" {
+			rules[column] = rule
+		}
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+	return &ShuffleDeck{
+		cfg:    cfg,
+		schema: schema,
+		rules:  rules,
+		values: make(map[string][]any),
+		piles:  make(map[string]map[string][]any),
+	}
+}
+
+// Columns returns the columns the first pass has to read: the shuffled
+// columns, their partition columns and the columns their conditions test
+func (d *ShuffleDeck) Columns() []string {
+	set := make(map[string]struct{})
+	for column, rule := range d.rules {
+		set[column] = struct{}{}
+		if rule.Partition != "" {
+			set[rule.Partition] = struct{}{}
+		}
+		for _, cond := range append(append(config.Conditions{}, rule.When...), rule.Unless...) {
+			set[cond.Column] = struct{}{}
+		}
+	}
+	columns := make([]string, 0, len(set))
+	for column := range set {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// pileKey identifies the values of a column within one partition
+func pileKey(column string, rule config.ColumnRule, row map[string]any) string {
+	if rule.Partition == "" {
+		return column
+	}
+	partition := row[rule.Partition]
+	if partition == nil {
+		return column + "\x00null"
+	}
+	return column + "\x00=" + stringValue(partition)
+}
+
+// Add collects the values of a row read in the first pass. Like Anonymize,
+// it passes over empty values and rows a rule's conditions exclude.
+func (d *ShuffleDeck) Add(row map[string]any) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, col := range d.schema.Columns {
+		rule, ok := d.rules[col.Name]
+		if !ok {
+			continue
+		}
+		val := row[col.Name]
+		if columnEmpty(col, val) || !appliesTo(rule, row) {
+			continue
+		}
+		key := pileKey(col.Name, rule, row)
+		d.values[key] = append(d.values[key], val)
+	}
+}
+
+// Shuffle pairs each collected value at random with a row's original value
+// in the same column and partition, such that no value is paired with an
+// equal one wherever that can be done. With a key configured the pairing
+// only depends on the key and the values read. Knowing it would undo the
+// shuffle, so it never derives from the printed seed.
+func (d *ShuffleDeck) Shuffle() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	keys := make([]string, 0, len(d.values))
+	for key := range d.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		// Sorted first, so the order the first pass read rows in doesn't matter
+		originals := d.values[key]
+		sort.SliceStable(originals, func(i, j int) bool { return stringValue(originals[i]) < stringValue(originals[j]) })
+		f := fakerFor(d.cfg, "shuffle", d.schema.Name+"\x00"+key)
+		deal := append([]any(nil), originals...)
+		f.ShuffleAnySlice(deal)
+		derange(f, originals, deal)
+
+		pile := make(map[string][]any)
+		for i, val := range deal {
+			original := stringValue(originals[i])
+			pile[original] = append(pile[original], val)
+		}
+		d.piles[key] = pile
+	}
+}
+
+// derange swaps values of deal until none equals the original at the same
+// index, as far as possible: it can't be done when one value makes up more
+// than half of them.
+func derange(f *gofakeit.Faker, originals, deal []any) {
+	n := len(deal)
+	for i := range deal {
+		if stringValue(deal[i]) != stringValue(originals[i]) {
+			continue
+		}
+		// Look for a swap that leaves neither index with its own value,
+		// starting anywhere so the pairing stays random
+		start := f.IntN(n)
+		for k := 0; k < n; k++ {
+			j := (start + k) % n
+			if stringValue(deal[j]) != stringValue(originals[i]) && stringValue(deal[i]) != stringValue(originals[j]) {
+				deal[i], deal[j] = deal[j], deal[i]
+				break
+			}
+		}
+	}
+}
+
+// deal hands out the next value paired with the row's original value. A
+// row that can't be given another value that way, because the table grew
+// since the first pass or one value makes up most of its partition, draws
+// any other value of its partition instead. It reports false when the
+// partition has no value other than the original.
+func (d *ShuffleDeck) deal(f *gofakeit.Faker, column string, rule config.ColumnRule, row map[string]any) (any, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := pileKey(column, rule, row)
+	original := stringValue(row[column])
+	if pile := d.piles[key][original]; len(pile) > 0 {
+		val := pile[len(pile)-1]
+		d.piles[key][original] = pile[:len(pile)-1]
+		if stringValue(val) != original {
+			return val, true
+		}
+	}
+	var pool []any
+	for _, val := range d.values[key] {
+		if stringValue(val) != original {
+			pool = append(pool, val)
+		}
+	}
+	if len(pool) == 0 {
+		return nil, false
+	}
+	return pool[f.IntN(len(pool))], true
+}
+
+// shuffleDecks holds the deck of every table being shuffled
+var shuffleDecks sync.Map
+
+// UseShuffleDeck makes a filled and shuffled deck available to Anonymize
+func UseShuffleDeck(d *ShuffleDeck) {
+	shuffleDecks.Store(d.schema.Name, d)
+}
+
+// shuffledValue draws a value for the column from its table's deck. A row
+// never keeps its own value: when the column has no other value, a fresh
+// value for its type is generated instead. A partitioned column can't take
+// a value from outside the row's partition, so that row fails.
+func shuffledValue(f *gofakeit.Faker, in *Input) (any, error) {
+	deck, ok := shuffleDecks.Load(in.state.schema.Name)
+	if !ok {
+		return nil, fmt.Errorf("no shuffle deck loaded for table %s", in.state.schema.Name)
+	}
+	if val, ok := deck.(*ShuffleDeck).deal(f, in.Column.Name, in.Rule, in.state.original); ok {
+		return val, nil
+	}
+	if in.Rule.Partition != "" {
+		return nil, badValue(fmt.Errorf("no other value to shuffle with where %s is %v", in.Rule.Partition, in.state.original[in.Rule.Partition]))
+	}
+	fresh := config.ColumnRule{Strategy: guessKind(in.Column), Locale: in.Rule.Locale, LocaleFrom: in.Rule.LocaleFrom}
+	return transform(in.Config, in.state, in.Row, in.Column, fresh, in.Value)
+}
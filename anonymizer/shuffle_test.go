@@ -0,0 +1,144 @@
+package anonymizer
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/andys/new_names/config"
+	"github.com/andys/new_names/db"
+	"github.com/frankban/quicktest"
+)
+
+func shuffleConfig(table string, rule config.ColumnRule) *config.Config {
+	return &config.Config{
+		AnonymizeFields: map[string][]string{table: {"city"}},
+		ColumnRules:     map[string]map[string]config.ColumnRule{table: {"city": rule}},
+	}
+}
+
+func shuffleSchema(table string) *db.TableSchema {
+	return &db.TableSchema{
+		Name:    table,
+		Columns: []db.ColumnSchema{{Name: "id", Type: "integer"}, {Name: "country", Type: "varchar"}, {Name: "city", Type: "varchar"}},
+	}
+}
+
+func TestShuffle_KeepsColumnValues(t *testing.T) {
+	c := quicktest.New(t)
+	schema := shuffleSchema("shuffle_all")
+	cfg := shuffleConfig(schema.Name, config.ColumnRule{Strategy: "shuffle"})
+	cities := []any{"Leeds", "York", "Hull", "Bath", "Ely", "Ripon", "Wells", "Truro", nil}
+
+	deck := NewShuffleDeck(schema, cfg)
+	c.Assert(deck.Columns(), quicktest.DeepEquals, []string{"city"})
+	for _, city := range cities {
+		deck.Add(map[string]any{"city": city})
+	}
+	deck.Shuffle()
+	UseShuffleDeck(deck)
+
+	var got []string
+	moved := 0
+	for i, city := range cities {
+		row := &Row{Schema: schema, Data: map[string]interface{}{"id": int64(i), "city": city}}
+		c.Assert(Anonymize(row, cfg), quicktest.IsNil)
+		if city == nil {
+			c.Assert(row.Data["city"], quicktest.IsNil)
+			continue
+		}
+		got = append(got, row.Data["city"].(string))
+		if row.Data["city"] != city {
+			moved++
+		}
+	}
+	sort.Strings(got)
+	c.Assert(got, quicktest.DeepEquals, []string{"Bath", "Ely", "Hull", "Leeds", "Ripon", "Truro", "Wells", "York"})
+	c.Assert(moved > 0, quicktest.IsTrue)
+}
+
+func TestShuffle_WithinPartition(t *testing.T) {
+	c := quicktest.New(t)
+	schema := shuffleSchema("shuffle_partitioned")
+	cfg := shuffleConfig(schema.Name, config.ColumnRule{Strategy: "shuffle", Partition: "country"})
+	rows := []map[string]any{
+		{"country": "UK", "city": "Leeds"}, {"country": "UK", "city": "York"}, {"country": "UK", "city": "Hull"},
+		{"country": "DE", "city": "Köln"}, {"country": "DE", "city": "Bonn"},
+	}
+
+	deck := NewShuffleDeck(schema, cfg)
+	c.Assert(deck.Columns(), quicktest.DeepEquals, []string{"city", "country"})
+	for _, data := range rows {
+		deck.Add(data)
+	}
+	deck.Shuffle()
+	UseShuffleDeck(deck)
+
+	for _, data := range rows {
+		row := &Row{Schema: schema, Data: map[string]interface{}{"country": data["country"], "city": data["city"]}}
+		c.Assert(Anonymize(row, cfg), quicktest.IsNil)
+		if data["country"] == "UK" {
+			c.Assert([]any{"Leeds", "York", "Hull"}, quicktest.Contains, row.Data["city"])
+		} else {
+			c.Assert([]any{"Köln", "Bonn"}, quicktest.Contains, row.Data["city"])
+		}
+	}
+
+	// A partition missing from the first pass has nothing to shuffle with
+	row := &Row{Schema: schema, Data: map[string]interface{}{"country": "FR", "city": "Lyon"}}
+	err := Anonymize(row, cfg)
+	c.Assert(err, quicktest.ErrorMatches, `column city: no other value to shuffle with where country is FR`)
+	c.Assert(err, quicktest.ErrorIs, ErrBadValue)
+}
+
+func TestShuffle_NeverDealsOwnValue(t *testing.T) {
+	c := quicktest.New(t)
+	schema := shuffleSchema("shuffle_pairs")
+	cfg := shuffleConfig(schema.Name, config.ColumnRule{Strategy: "shuffle", Partition: "country"})
+	rows := []map[string]any{
+		{"country": "UK", "city": "Leeds"}, {"country": "UK", "city": "York"},
+		{"country": "DE", "city": "Köln"}, {"country": "DE", "city": "Köln"}, {"country": "DE", "city": "Bonn"},
+	}
+
+	for run := 0; run < 20; run++ {
+		deck := NewShuffleDeck(schema, cfg)
+		for _, data := range rows {
+			deck.Add(data)
+		}
+		deck.Shuffle()
+		UseShuffleDeck(deck)
+
+		for _, data := range rows {
+			row := &Row{Schema: schema, Data: map[string]interface{}{"country": data["country"], "city": data["city"]}}
+			c.Assert(Anonymize(row, cfg), quicktest.IsNil)
+			c.Assert(row.Data["city"], quicktest.Not(quicktest.Equals), data["city"])
+		}
+	}
+}
+
+func TestShuffle_NeverKeepsOriginalWithoutValues(t *testing.T) {
+	c := quicktest.New(t)
+	schema := shuffleSchema("shuffle_empty")
+	cfg := shuffleConfig(schema.Name, config.ColumnRule{Strategy: "shuffle"})
+	deck := NewShuffleDeck(schema, cfg)
+	deck.Shuffle()
+	UseShuffleDeck(deck)
+
+	// The row appeared after the first pass, which found nothing to shuffle
+	row := &Row{Schema: schema, Data: map[string]interface{}{"city": "Leeds"}}
+	c.Assert(Anonymize(row, cfg), quicktest.IsNil)
+	c.Assert(row.Data["city"], quicktest.Not(quicktest.Equals), "Leeds")
+	c.Assert(row.Data["city"], quicktest.Not(quicktest.Equals), "")
+}
+
+func TestShuffle_NeedsDeck(t *testing.T) {
+	c := quicktest.New(t)
+	schema := shuffleSchema("shuffle_unloaded")
+	cfg := shuffleConfig(schema.Name, config.ColumnRule{Strategy: "shuffle"})
+
+	row := &Row{Schema: schema, Data: map[string]interface{}{"city": "Leeds"}}
+	c.Assert(Anonymize(row, cfg), quicktest.ErrorMatches, `column city: no shuffle deck loaded for table shuffle_unloaded`)
+	c.Assert(NewShuffleDeck(schema, &config.Config{}), quicktest.IsNil)
+
+	cfg = shuffleConfig(schema.Name, config.ColumnRule{Strategy: "shuffle", Partition: "region"})
+	c.Assert(Validate(cfg, []db.TableSchema{*schema}), quicktest.ErrorMatches, `column shuffle_unloaded.city: partition column region does not exist`)
+}
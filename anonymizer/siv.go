@@ -0,0 +1,147 @@
+package anonymizer
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+)
+
+// sivTagSize is the size of the synthetic IV, which doubles as the
+// authentication tag
+const sivTagSize = aes.BlockSize
+
+// errTokenInvalid is returned for ciphertexts that weren't produced under the key
+var errTokenInvalid = errors.New("token is invalid or was made with another key")
+
+// siv implements AES-SIV (RFC 5297), a deterministic authenticated
+// encryption mode: equal plaintexts give equal ciphertexts, and any
+// tampering is detected on decryption.
+type siv struct {
+	mac cipher.Block // Keyed with the first half of the key, for S2V
+	ctr cipher.Block // Keyed with the second half, for encryption
+}
+
+// newSIV takes a 32, 48 or 64 byte key, for AES-128, -192 or -256
+func newSIV(key []byte) (*siv, error) {
+	switch len(key) {
+	case 32, 48, 64:
+	default:
+		return nil, fmt.Errorf("AES-SIV key must be 32, 48 or 64 bytes, not %d", len(key))
+	}
+	half := len(key) / 2
+	mac, err := aes.NewCipher(key[:half])
+	if err != nil {
+		return nil, err
+	}
+	ctr, err := aes.NewCipher(key[half:])
+	if err != nil {
+		return nil, err
+	}
+	return &siv{mac: mac, ctr: ctr}, nil
+}
+
+// dbl doubles a block in GF(2^128), as used by CMAC and S2V
+func dbl(b []byte) []byte {
+	out := make([]byte, len(b))
+	carry := b[0] >> 7
+	for i := 0; i < len(b)-1; i++ {
+		out[i] = b[i]<<1 | b[i+1]>>7
+	}
+	out[len(b)-1] = b[len(b)-1] << 1
+	if carry != 0 {
+		out[len(b)-1] ^= 0x87
+	}
+	return out
+}
+
+func xorInto(dst, src []byte) {
+	for i := range src {
+		dst[i] ^= src[i]
+	}
+}
+
+// cmac computes the AES-CMAC (RFC 4493) of msg
+func cmac(block cipher.Block, msg []byte) []byte {
+	l := make([]byte, aes.BlockSize)
+	block.Encrypt(l, l)
+	k1 := dbl(l)
+	k2 := dbl(k1)
+
+	n := (len(msg) + aes.BlockSize - 1) / aes.BlockSize
+	last := make([]byte, aes.BlockSize)
+	if n == 0 || len(msg)%aes.BlockSize != 0 {
+		// Pad the incomplete final block with 10...0
+		if n == 0 {
+			n = 1
+		}
+		copy(last, msg[(n-1)*aes.BlockSize:])
+		last[len(msg)-(n-1)*aes.BlockSize] = 0x80
+		xorInto(last, k2)
+	} else {
+		copy(last, msg[(n-1)*aes.BlockSize:])
+		xorInto(last, k1)
+	}
+
+	x := make([]byte, aes.BlockSize)
+	for i := 0; i < n-1; i++ {
+		xorInto(x, msg[i*aes.BlockSize:(i+1)*aes.BlockSize])
+		block.Encrypt(x, x)
+	}
+	xorInto(x, last)
+	block.Encrypt(x, x)
+	return x
+}
+
+// s2v derives the synthetic IV from the associated data and plaintext
+func (s *siv) s2v(plaintext []byte, ad ...[]byte) []byte {
+	d := cmac(s.mac, make([]byte, aes.BlockSize))
+	for _, a := range ad {
+		d = dbl(d)
+		xorInto(d, cmac(s.mac, a))
+	}
+	var t []byte
+	if len(plaintext) >= aes.BlockSize {
+		t = append([]byte(nil), plaintext...)
+		xorInto(t[len(t)-aes.BlockSize:], d)
+	} else {
+		t = dbl(d)
+		padded := make([]byte, aes.BlockSize)
+		copy(padded, plaintext)
+		padded[len(plaintext)] = 0x80
+		xorInto(t, padded)
+	}
+	return cmac(s.mac, t)
+}
+
+// crypt applies AES-CTR keyed by the synthetic IV, with the two bits RFC
+// 5297 reserves cleared
+func (s *siv) crypt(v, in []byte) []byte {
+	iv := append([]byte(nil), v...)
+	iv[8] &= 0x7f
+	iv[12] &= 0x7f
+	out := make([]byte, len(in))
+	cipher.NewCTR(s.ctr, iv).XORKeyStream(out, in)
+	return out
+}
+
+// seal returns the synthetic IV followed by the encrypted plaintext
+func (s *siv) seal(plaintext []byte, ad ...[]byte) []byte {
+	v := s.s2v(plaintext, ad...)
+	return append(v, s.crypt(v, plaintext)...)
+}
+
+// open reverses seal, failing if the ciphertext was altered or made with
+// another key
+func (s *siv) open(ciphertext []byte, ad ...[]byte) ([]byte, error) {
+	if len(ciphertext) < sivTagSize {
+		return nil, errTokenInvalid
+	}
+	v := ciphertext[:sivTagSize]
+	plaintext := s.crypt(v, ciphertext[sivTagSize:])
+	if subtle.ConstantTimeCompare(s.s2v(plaintext, ad...), v) != 1 {
+		return nil, errTokenInvalid
+	}
+	return plaintext, nil
+}
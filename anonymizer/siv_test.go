@@ -0,0 +1,56 @@
+package anonymizer
+
+import (
+	"crypto/aes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/frankban/quicktest"
+)
+
+func unhex(c *quicktest.C, s string) []byte {
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	c.Assert(err, quicktest.IsNil)
+	return b
+}
+
+// Test vectors from RFC 4493, section 4
+func TestCMAC(t *testing.T) {
+	c := quicktest.New(t)
+	block, err := aes.NewCipher(unhex(c, "2b7e1516 28aed2a6 abf71588 09cf4f3c"))
+	c.Assert(err, quicktest.IsNil)
+
+	c.Assert(hex.EncodeToString(cmac(block, nil)), quicktest.Equals, "bb1d6929e95937287fa37d129b756746")
+	c.Assert(hex.EncodeToString(cmac(block, unhex(c, "6bc1bee2 2e409f96 e93d7e11 7393172a"))),
+		quicktest.Equals, "070a16b46b4d4144f79bdd9dd04a287c")
+	c.Assert(hex.EncodeToString(cmac(block, unhex(c,
+		"6bc1bee2 2e409f96 e93d7e11 7393172a ae2d8a57 1e03ac9c 9eb76fac 45af8e51 30c81c46 a35ce411"))),
+		quicktest.Equals, "dfa66747de9ae63030ca32611497c827")
+}
+
+// Test vector from RFC 5297, appendix A.1
+func TestSIV_DeterministicVector(t *testing.T) {
+	c := quicktest.New(t)
+	s, err := newSIV(unhex(c, "fffefdfc fbfaf9f8 f7f6f5f4 f3f2f1f0 f0f1f2f3 f4f5f6f7 f8f9fafb fcfdfeff"))
+	c.Assert(err, quicktest.IsNil)
+	ad := unhex(c, "10111213 14151617 18191a1b 1c1d1e1f 20212223 24252627")
+	plaintext := unhex(c, "11223344 55667788 99aabbcc ddee")
+
+	sealed := s.seal(plaintext, ad)
+	c.Assert(hex.EncodeToString(sealed), quicktest.Equals, "85632d07c6e8f37f950acd320a2ecc9340c02b9690c4dc04daef7f6afe5c")
+
+	opened, err := s.open(sealed, ad)
+	c.Assert(err, quicktest.IsNil)
+	c.Assert(opened, quicktest.DeepEquals, plaintext)
+
+	sealed[len(sealed)-1] ^= 1
+	_, err = s.open(sealed, ad)
+	c.Assert(err, quicktest.Equals, errTokenInvalid)
+}
+
+func TestNewSIV_KeySize(t *testing.T) {
+	c := quicktest.New(t)
+	_, err := newSIV(make([]byte, 16))
+	c.Assert(err, quicktest.ErrorMatches, `AES-SIV key must be 32, 48 or 64 bytes, not 16`)
+}
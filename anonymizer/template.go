@@ -0,0 +1,116 @@
+package anonymizer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/andys/new_names/config"
+	"github.com/andys/new_names/db"
+)
+
+// templateRef matches a column reference such as {{first_name}}, {{.first_name}}
+// or {{first_name | lower}}
+var templateRef = regexp.MustCompile(`\{\{\s*\.?([A-Za-z_][A-Za-z0-9_]*)\s*(?:\|\s*([a-z]+)\s*)?\}\}`)
+
+// templateFilters are the functions that can be applied to a reference with "|"
+var templateFilters = map[string]func(string) string{
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	// slug keeps only lowercase letters and digits, for building emails and usernames
+	"slug": func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return unicode.ToLower(r)
+			}
+			return -1
+		}, s)
+	},
+}
+
+// templateRefs returns the columns referenced by a template, in order of appearance
+func templateRefs(tmpl string) []string {
+	matches := templateRef.FindAllStringSubmatch(tmpl, -1)
+	refs := make([]string, 0, len(matches))
+	for _, m := range matches {
+		refs = append(refs, m[1])
+	}
+	return refs
+}
+
+// checkTemplate reports unknown columns and filters referenced by a template
+func checkTemplate(schema db.TableSchema, tmpl string) error {
+	for _, m := range templateRef.FindAllStringSubmatch(tmpl, -1) {
+		if !hasColumn(schema, m[1]) {
+			return fmt.Errorf("template refers to unknown column %q", m[1])
+		}
+		if _, ok := templateFilters[m[2]]; m[2] != "" && !ok {
+			return fmt.Errorf("template uses unknown filter %q", m[2])
+		}
+	}
+	return nil
+}
+
+// RenderTemplate replaces each {{column}} reference in tmpl with that
+// column's value from data. NULL and missing values render as empty strings.
+func RenderTemplate(tmpl string, data map[string]any) string {
+	return templateRef.ReplaceAllStringFunc(tmpl, func(ref string) string {
+		m := templateRef.FindStringSubmatch(ref)
+		val := data[m[1]]
+		if val == nil {
+			return ""
+		}
+		s := stringValue(val)
+		if filter, ok := templateFilters[m[2]]; ok {
+			s = filter(s)
+		}
+		return s
+	})
+}
+
+// columnOrder returns the table's columns in the order they should be
+// anonymized. Columns keep their schema order, except that a template column
+// always comes after the columns it refers to, so it sees their fake values.
+func columnOrder(schema *db.TableSchema, cfg *config.Config) ([]db.ColumnSchema, error) {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	byName := make(map[string]db.ColumnSchema, len(schema.Columns))
+	for _, col := range schema.Columns {
+		byName[col.Name] = col
+	}
+	ordered := make([]db.ColumnSchema, 0, len(schema.Columns))
+	state := make(map[string]int, len(schema.Columns))
+
+	var visit func(col db.ColumnSchema) error
+	visit = func(col db.ColumnSchema) error {
+		switch state[col.Name] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("template references form a cycle through column %s.%s", schema.Name, col.Name)
+		}
+		state[col.Name] = visiting
+		if rule := cfg.Rule(schema.Name, col.Name); rule.Strategy == "template" {
+			for _, ref := range templateRefs(rule.Template) {
+				if dep, ok := byName[ref]; ok {
+					if err := visit(dep); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		state[col.Name] = done
+		ordered = append(ordered, col)
+		return nil
+	}
+
+	for _, col := range schema.Columns {
+		if err := visit(col); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
@@ -0,0 +1,76 @@
+package anonymizer
+
+import (
+	"testing"
+
+	"github.com/andys/new_names/config"
+	"github.com/andys/new_names/db"
+	"github.com/frankban/quicktest"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	c := quicktest.New(t)
+	data := map[string]any{
+		"first_name": "Mary Ann",
+		"last_name":  []byte("O'Neil"),
+		"middle":     nil,
+	}
+
+	c.Assert(RenderTemplate("{{first_name}} {{ .last_name }}", data), quicktest.Equals, "Mary Ann O'Neil")
+	c.Assert(RenderTemplate("{{first_name | slug}}.{{last_name|slug}}@example.test", data), quicktest.Equals, "maryann.oneil@example.test")
+	c.Assert(RenderTemplate("{{last_name | upper}}{{middle}}", data), quicktest.Equals, "O'NEIL")
+}
+
+func TestColumnOrder_TemplatesAfterDependencies(t *testing.T) {
+	c := quicktest.New(t)
+	schema := &db.TableSchema{
+		Name: "users",
+		Columns: []db.ColumnSchema{
+			{Name: "id"},
+			{Name: "email"},
+			{Name: "display_name"},
+			{Name: "first_name"},
+			{Name: "last_name"},
+		},
+	}
+	cfg := &config.Config{
+		ColumnRules: map[string]map[string]config.ColumnRule{
+			"users": {
+				"email":        {Strategy: "template", Template: "{{display_name | slug}}@example.test"},
+				"display_name": {Strategy: "template", Template: "{{first_name}} {{last_name}}"},
+			},
+		},
+	}
+
+	cols, err := columnOrder(schema, cfg)
+	c.Assert(err, quicktest.IsNil)
+	names := make([]string, len(cols))
+	for i, col := range cols {
+		names[i] = col.Name
+	}
+	c.Assert(names, quicktest.DeepEquals, []string{"id", "first_name", "last_name", "display_name", "email"})
+}
+
+func TestValidate_TemplateErrors(t *testing.T) {
+	c := quicktest.New(t)
+	schemas := []db.TableSchema{{
+		Name:    "users",
+		Columns: []db.ColumnSchema{{Name: "a"}, {Name: "b"}},
+	}}
+	cfg := &config.Config{
+		ColumnRules: map[string]map[string]config.ColumnRule{
+			"users": {
+				"a": {Strategy: "template", Template: "{{b}}"},
+				"b": {Strategy: "template", Template: "{{a}}"},
+			},
+		},
+	}
+	c.Assert(Validate(cfg, schemas), quicktest.ErrorMatches, "template references form a cycle through column users.a")
+
+	cfg.ColumnRules["users"]["b"] = config.ColumnRule{Strategy: "template", Template: "{{c}}"}
+	c.Assert(Validate(cfg, schemas), quicktest.ErrorMatches, `column users.b: template refers to unknown column "c"`)
+
+	cfg.ColumnRules["users"]["b"] = config.ColumnRule{Strategy: "lorem"}
+	cfg.ColumnRules["users"]["a"] = config.ColumnRule{Strategy: "template", Template: "{{b | title}}"}
+	c.Assert(Validate(cfg, schemas), quicktest.ErrorMatches, `column users.a: template uses unknown filter "title"`)
+}
@@ -0,0 +1,81 @@
+package anonymizer
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/andys/new_names/db"
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// tokenPrefix marks tokenized values, so they can be told apart from data
+const tokenPrefix = "tok_"
+
+// tokenEncoding keeps tokens short and safe in URLs and CSV files
+var tokenEncoding = base64.RawURLEncoding
+
+// ErrValueTooLong is returned for a row whose replacement value won't fit
+// its column and can't be cut short, as with tokens. It is an ErrBadValue.
+var ErrValueTooLong error = valueError{errors.New("value too long for the column")}
+
+// tokenLength returns the length of the token for a plaintext of n bytes
+func tokenLength(n int) int {
+	return len(tokenPrefix) + tokenEncoding.EncodedLen(sivTagSize+n)
+}
+
+// Tokenize encrypts a value into an opaque token with AES-SIV. The same
+// value always gives the same token under a key, so joins survive, and
+// only holders of the key can turn the token back with Detokenize.
+func Tokenize(key []byte, value string) (string, error) {
+	s, err := newSIV(key)
+	if err != nil {
+		return "", err
+	}
+	return tokenPrefix + tokenEncoding.EncodeToString(s.seal([]byte(value))), nil
+}
+
+// Detokenize returns the value a token was made from
+func Detokenize(key []byte, token string) (string, error) {
+	s, err := newSIV(key)
+	if err != nil {
+		return "", err
+	}
+	encoded, ok := strings.CutPrefix(strings.TrimSpace(token), tokenPrefix)
+	if !ok {
+		return "", fmt.Errorf("not a token: missing %q prefix", tokenPrefix)
+	}
+	ciphertext, err := tokenEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("not a token: %w", err)
+	}
+	plaintext, err := s.open(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// tokenizeValue replaces a value with its token. Tokens can't be cut short
+// without losing the value, so one too long for the column fails the row.
+func tokenizeValue(_ *gofakeit.Faker, in *Input) (any, error) {
+	token, err := Tokenize(in.Config.TokenKey, stringValue(in.Value))
+	if err != nil {
+		return nil, err
+	}
+	if in.Column.MaxLength > 0 && utf8.RuneCountInString(token) > in.Column.MaxLength {
+		return nil, fmt.Errorf("%w: token has %d characters, the column holds %d", ErrValueTooLong, len(token), in.Column.MaxLength)
+	}
+	return token, nil
+}
+
+// tokenizable reports whether a column can hold tokens, which are text
+func tokenizable(col db.ColumnSchema) bool {
+	switch guessKind(col) {
+	case "email", "phone", "name", "text":
+		return true
+	}
+	return false
+}
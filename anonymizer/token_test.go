@@ -0,0 +1,89 @@
+package anonymizer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/andys/new_names/config"
+	"github.com/andys/new_names/db"
+	"github.com/frankban/quicktest"
+)
+
+var testTokenKey = bytes.Repeat([]byte{0x42}, 64)
+
+func TestTokenize_RoundTrip(t *testing.T) {
+	c := quicktest.New(t)
+	token, err := Tokenize(testTokenKey, "jane.doe@example.com")
+	c.Assert(err, quicktest.IsNil)
+	c.Assert(token, quicktest.Matches, `tok_[A-Za-z0-9_-]+`)
+	c.Assert(strings.Contains(token, "jane"), quicktest.IsFalse)
+	c.Assert(len(token), quicktest.Equals, tokenLength(len("jane.doe@example.com")))
+
+	again, err := Tokenize(testTokenKey, "jane.doe@example.com")
+	c.Assert(err, quicktest.IsNil)
+	c.Assert(again, quicktest.Equals, token)
+
+	value, err := Detokenize(testTokenKey, token+"\n")
+	c.Assert(err, quicktest.IsNil)
+	c.Assert(value, quicktest.Equals, "jane.doe@example.com")
+
+	otherKey := bytes.Repeat([]byte{0x24}, 64)
+	_, err = Detokenize(otherKey, token)
+	c.Assert(err, quicktest.ErrorMatches, `token is invalid or was made with another key`)
+	_, err = Detokenize(testTokenKey, "jane.doe@example.com")
+	c.Assert(err, quicktest.ErrorMatches, `not a token: missing "tok_" prefix`)
+}
+
+func TestAnonymize_Tokenize(t *testing.T) {
+	c := quicktest.New(t)
+	schema := &db.TableSchema{
+		Name: "customers",
+		Columns: []db.ColumnSchema{
+			{Name: "email", Type: "varchar", MaxLength: 80},
+			{Name: "ref", Type: "varchar", MaxLength: 30},
+		},
+	}
+	cfg := &config.Config{
+		TokenKey:        testTokenKey,
+		AnonymizeFields: map[string][]string{"customers": {"email", "ref"}},
+		ColumnRules: map[string]map[string]config.ColumnRule{"customers": {
+			"email": {Strategy: "tokenize"},
+			"ref":   {Strategy: "tokenize"},
+		}},
+	}
+	c.Assert(Validate(cfg, []db.TableSchema{*schema}), quicktest.IsNil)
+
+	row := &Row{Schema: schema, Data: map[string]interface{}{"email": []byte("jane@example.com"), "ref": "A1"}}
+	c.Assert(Anonymize(row, cfg), quicktest.IsNil)
+	value, err := Detokenize(testTokenKey, row.Data["email"].(string))
+	c.Assert(err, quicktest.IsNil)
+	c.Assert(value, quicktest.Equals, "jane@example.com")
+
+	row = &Row{Schema: schema, Data: map[string]interface{}{"email": "x", "ref": "ABCDEFGHIJ"}}
+	err = Anonymize(row, cfg)
+	c.Assert(err, quicktest.ErrorMatches, `column ref: value too long for the column: token has 39 characters, the column holds 30`)
+	c.Assert(err, quicktest.ErrorIs, ErrValueTooLong)
+	c.Assert(err, quicktest.ErrorIs, ErrBadValue)
+}
+
+func TestValidate_Tokenize(t *testing.T) {
+	c := quicktest.New(t)
+	schemas := []db.TableSchema{{
+		Name: "customers",
+		Columns: []db.ColumnSchema{
+			{Name: "id", Type: "bigint"},
+			{Name: "code", Type: "char", MaxLength: 8},
+		},
+	}}
+	validate := func(column string, key []byte) error {
+		return Validate(&config.Config{
+			TokenKey:    key,
+			ColumnRules: map[string]map[string]config.ColumnRule{"customers": {column: {Strategy: "tokenize"}}},
+		}, schemas)
+	}
+
+	c.Assert(validate("code", nil), quicktest.ErrorMatches, `column customers.code: tokenize strategy requires a token key file`)
+	c.Assert(validate("id", testTokenKey), quicktest.ErrorMatches, `column customers.id: tokenize strategy needs a text column, not bigint`)
+	c.Assert(validate("code", testTokenKey), quicktest.ErrorMatches, `column customers.code: tokens need at least 27 characters, the column holds 8`)
+}
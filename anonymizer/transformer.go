@@ -0,0 +1,104 @@
+package anonymizer
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/andys/new_names/config"
+	"github.com/andys/new_names/db"
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// Input is an original value together with the column and rule it is anonymized under
+type Input struct {
+	Config *config.Config
+	Value  any
+	Row    map[string]any // The row's values, including replacements made so far
+	Column db.ColumnSchema
+	Rule   config.ColumnRule
+	MaxLen int // Length fake strings should be generated for; values are truncated to Column.MaxLength afterwards
+
+	state  *rowState
+	locale *localeData // Nil for the default locale
+}
+
+// Original returns the value a column of the row had before anonymization
+func (in *Input) Original(column string) any {
+	if in.state == nil {
+		return in.Row[column]
+	}
+	return in.state.original[column]
+}
+
+// Transformer produces the replacement for a value. The faker should be
+// the only source of randomness, as it is seeded per value when a key is
+// configured so that the same value is always replaced the same way.
+type Transformer interface {
+	Transform(f *gofakeit.Faker, in *Input) (any, error)
+}
+
+// TransformerFunc adapts a function to the Transformer interface
+type TransformerFunc func(f *gofakeit.Faker, in *Input) (any, error)
+
+// Transform calls fn(f, in)
+func (fn TransformerFunc) Transform(f *gofakeit.Faker, in *Input) (any, error) {
+	return fn(f, in)
+}
+
+// RuleValidator is implemented by transformers that check their options.
+// Validate is called for every column configured to use the transformer,
+// before any data is copied.
+type RuleValidator interface {
+	ValidateRule(col db.ColumnSchema, rule config.ColumnRule) error
+}
+
+// transformers holds the registered transformers by strategy name
+var transformers = struct {
+	sync.RWMutex
+	byName map[string]Transformer
+}{byName: make(map[string]Transformer)}
+
+// Register makes a transformer available as a strategy in the config file.
+// It is meant to be called from an init function, and panics if the name is
+// empty or already taken, or the transformer is nil.
+func Register(name string, t Transformer) {
+	transformers.Lock()
+	defer transformers.Unlock()
+	if name == "" || t == nil {
+		panic("anonymizer: Register needs a name and a transformer")
+	}
+	if _, dup := transformers.byName[name]; dup {
+		panic(fmt.Sprintf("anonymizer: Register called twice for strategy %q", name))
+	}
+	transformers.byName[name] = t
+}
+
+// lookupTransformer returns the transformer registered under name
+func lookupTransformer(name string) (Transformer, bool) {
+	transformers.RLock()
+	defer transformers.RUnlock()
+	t, ok := transformers.byName[name]
+	return t, ok
+}
+
+// Strategies returns the names of all registered transformers, sorted
+func Strategies() []string {
+	transformers.RLock()
+	defer transformers.RUnlock()
+	names := make([]string, 0, len(transformers.byName))
+	for name := range transformers.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	for name, fn := range builtinTransformers {
+		Register(name, fn)
+	}
+	for name, generate := range identifierGenerators {
+		Register(name, identifierTransformer(generate))
+	}
+}
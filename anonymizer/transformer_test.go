@@ -0,0 +1,72 @@
+package anonymizer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/andys/new_names/config"
+	"github.com/andys/new_names/db"
+	"github.com/brianvoe/gofakeit/v7"
+	"github.com/frankban/quicktest"
+)
+
+// policyNumber is a custom transformer generating policy numbers such as
+// POL-1234567-8, whose last digit is a checksum
+type policyNumber struct{}
+
+func (policyNumber) Transform(f *gofakeit.Faker, in *Input) (any, error) {
+	prefix, _ := in.Rule.Options["prefix"].(string)
+	digits := f.Numerify("#######")
+	sum := 0
+	for _, d := range digits {
+		sum += int(d - '0')
+	}
+	return fmt.Sprintf("%s-%s-%d", prefix, digits, sum%10), nil
+}
+
+func (policyNumber) ValidateRule(_ db.ColumnSchema, rule config.ColumnRule) error {
+	if _, ok := rule.Options["prefix"].(string); !ok {
+		return fmt.Errorf("policy_number needs a prefix option")
+	}
+	return nil
+}
+
+func init() {
+	Register("policy_number", policyNumber{})
+	Register("shout", TransformerFunc(func(_ *gofakeit.Faker, in *Input) (any, error) {
+		return strings.ToUpper(stringValue(in.Original("number"))), nil
+	}))
+}
+
+func TestRegister_CustomTransformer(t *testing.T) {
+	c := quicktest.New(t)
+	schemas := []db.TableSchema{{
+		Name:    "policies",
+		Columns: []db.ColumnSchema{{Name: "number", Type: "varchar"}, {Name: "holder", Type: "varchar"}},
+	}}
+	cfg := &config.Config{
+		AnonymizeFields: map[string][]string{"policies": {"number", "holder"}},
+		ColumnRules: map[string]map[string]config.ColumnRule{"policies": {
+			"number": {Strategy: "policy_number", Options: map[string]any{"prefix": "POL"}},
+			"holder": {Strategy: "shout"},
+		}},
+	}
+
+	c.Assert(Strategies(), quicktest.Contains, "policy_number")
+	c.Assert(Validate(cfg, schemas), quicktest.IsNil)
+
+	row := &Row{Schema: &schemas[0], Data: map[string]interface{}{"number": "pol-0000001-1", "holder": "Jane"}}
+	c.Assert(Anonymize(row, cfg), quicktest.IsNil)
+	c.Assert(row.Data["number"], quicktest.Matches, `POL-\d{7}-\d`)
+	c.Assert(row.Data["holder"], quicktest.Equals, "POL-0000001-1")
+
+	cfg.ColumnRules["policies"]["number"] = config.ColumnRule{Strategy: "policy_number"}
+	c.Assert(Validate(cfg, schemas), quicktest.ErrorMatches, `column policies.number: policy_number needs a prefix option`)
+}
+
+func TestRegister_PanicsOnDuplicate(t *testing.T) {
+	c := quicktest.New(t)
+	c.Assert(func() { Register("email", policyNumber{}) }, quicktest.PanicMatches, `anonymizer: Register called twice for strategy "email"`)
+	c.Assert(func() { Register("", policyNumber{}) }, quicktest.PanicMatches, `anonymizer: Register needs a name and a transformer`)
+}
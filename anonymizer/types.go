@@ -0,0 +1,360 @@
+package anonymizer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/andys/new_names/config"
+	"github.com/andys/new_names/db"
+	"github.com/brianvoe/gofakeit/v7"
+)
+
+// logicalType returns the column's normalized type, working it out from the
+// type name for columns that weren't loaded from a database schema
+func logicalType(col db.ColumnSchema) db.LogicalType {
+	if col.Logical != "" {
+		return col.Logical
+	}
+	return db.LogicalTypeOf("", col)
+}
+
+// textKinds are the generators for text-like types that take a fixed format
+var textKinds = map[string]string{
+	"inet": "ipv4", "cidr": "ipv4", "macaddr": "mac_address", "macaddr8": "mac_address",
+	"bit": "bits", "bit varying": "bits", "varbit": "bits",
+}
+
+// integerBits is the width of each integer type
+var integerBits = map[string]int{
+	"tinyint": 8, "smallint": 16, "int2": 16, "smallserial": 16, "mediumint": 24,
+	"int": 32, "integer": 32, "int4": 32, "serial": 32,
+	"bigint": 64, "int8": 64, "bigserial": 64,
+}
+
+// integerValue returns a random integer that fits the column's type
+func integerValue(f *gofakeit.Faker, in *Input) (any, error) {
+	colType := strings.ToLower(in.Column.Type)
+	if colType == "year" {
+		// The range of MySQL's YEAR type
+		return int64(f.IntRange(1901, 2155)), nil
+	}
+	bits, ok := integerBits[colType]
+	switch {
+	case !ok:
+		return f.Int64(), nil
+	case in.Column.Unsigned && bits == 64:
+		return f.Uint64(), nil
+	case in.Column.Unsigned:
+		return int64(f.Uint64() >> (64 - bits)), nil
+	case strings.HasSuffix(colType, "serial"):
+		// Serials count up from 1
+		return int64(f.Uint64()>>(65-bits)) + 1, nil
+	}
+	// An arithmetic shift keeps the sign, giving the type's full signed range
+	return int64(f.Uint64()) >> (64 - bits), nil
+}
+
+// floatValue returns a random float, in single precision for columns that store one
+func floatValue(f *gofakeit.Faker, in *Input) (any, error) {
+	switch strings.ToLower(in.Column.Type) {
+	case "float", "real", "float4":
+		return float64(f.Float32()), nil
+	}
+	return f.Float64(), nil
+}
+
+// defaultDecimalDigits is how many digits go before the point of unconstrained
+// numeric columns when the original doesn't suggest a size
+const defaultDecimalDigits = 6
+
+// decimalValue returns a decimal number as text that fits the column's
+// precision and scale. It has as many digits before the point as the
+// original where the column allows, and the original's sign.
+func decimalValue(f *gofakeit.Faker, in *Input) (any, error) {
+	precision, scale := in.Column.Precision, in.Column.Scale
+	n, places, err := numericValue(in.Value)
+	if precision == 0 {
+		// Unconstrained numeric columns keep the original's decimal places
+		scale = max(places, 0)
+		if strings.EqualFold(in.Column.Type, "money") {
+			scale = 2
+		}
+		precision = scale + max(defaultDecimalDigits, len(strconv.FormatFloat(math.Abs(n), 'f', 0, 64)))
+	}
+	wholeDigits := precision - scale
+	if err == nil && n != 0 {
+		wholeDigits = min(wholeDigits, len(strconv.FormatFloat(math.Trunc(math.Abs(n)), 'f', 0, 64)))
+	}
+
+	whole := "0"
+	if wholeDigits > 0 {
+		whole = strings.TrimLeft(randomDigits(f, f.IntRange(1, wholeDigits)), "0")
+		if whole == "" {
+			whole = "0"
+		}
+	}
+	s := whole
+	if scale > 0 {
+		s += "." + randomDigits(f, scale)
+	}
+	if n < 0 && !in.Column.Unsigned {
+		s = "-" + s
+	}
+	if _, ok := in.Value.(float64); ok {
+		return strconv.ParseFloat(s, 64)
+	}
+	return s, nil
+}
+
+// booleanValue returns a random boolean, as 0 or 1 where the original was a
+// number. MySQL has no boolean type and returns those of tinyint(1) columns
+// as integers, or as the text "0" or "1".
+func booleanValue(f *gofakeit.Faker, in *Input) (any, error) {
+	b := f.Bool()
+	numeric := false
+	switch in.Value.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		numeric = true
+	case []byte, string:
+		s := stringValue(in.Value)
+		numeric = s == "0" || s == "1"
+	}
+	if !numeric {
+		return b, nil
+	}
+	if b {
+		return int64(1), nil
+	}
+	return int64(0), nil
+}
+
+// enumValue picks one of the column's allowed values
+func enumValue(f *gofakeit.Faker, in *Input) (any, error) {
+	return in.Column.EnumValues[f.IntN(len(in.Column.EnumValues))], nil
+}
+
+// setValue picks a non-empty subset of the column's allowed values, in
+// their declared order as MySQL stores them
+func setValue(f *gofakeit.Faker, in *Input) (any, error) {
+	values := in.Column.EnumValues
+	var picked []string
+	for _, v := range values {
+		if f.Bool() {
+			picked = append(picked, v)
+		}
+	}
+	if len(picked) == 0 {
+		picked = append(picked, values[f.IntN(len(values))])
+	}
+	return strings.Join(picked, ","), nil
+}
+
+// timeValue returns a time of day
+func timeValue(f *gofakeit.Faker, _ *Input) (any, error) {
+	return fmt.Sprintf("%02d:%02d:%02d", f.IntN(24), f.IntN(60), f.IntN(60)), nil
+}
+
+// intervalValue returns a PostgreSQL interval of up to a year
+func intervalValue(f *gofakeit.Faker, _ *Input) (any, error) {
+	return fmt.Sprintf("%d days %02d:%02d:%02d", f.IntN(366), f.IntN(24), f.IntN(60), f.IntN(60)), nil
+}
+
+// bitsValue returns a bit string for PostgreSQL bit columns, which report
+// their length, or a number that fits in a MySQL bit column's width
+func bitsValue(f *gofakeit.Faker, in *Input) (any, error) {
+	if n := in.Column.MaxLength; n > 0 {
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = byte('0' + f.IntN(2))
+		}
+		return string(b), nil
+	}
+	width := in.Column.Precision
+	if width <= 0 || width > 63 {
+		width = 63
+	}
+	return int64(f.Uint64() >> (64 - width)), nil
+}
+
+// pgPoint formats a point the way PostgreSQL's geometric types write it
+func pgPoint(x, y float64) string {
+	return "(" + strconv.FormatFloat(x, 'f', -1, 64) + "," + strconv.FormatFloat(y, 'f', -1, 64) + ")"
+}
+
+// wkbTypes are the WKB geometry type codes of MySQL's spatial types
+var wkbTypes = map[string]uint32{
+	"geometry": 1, "point": 1, "linestring": 2, "polygon": 3,
+	"multipoint": 4, "multilinestring": 5, "multipolygon": 6, "geometrycollection": 7,
+}
+
+// mysqlGeometry reports whether val is a value in MySQL's internal geometry
+// format: a 4-byte SRID followed by WKB, which starts with a byte order
+// marker of 0 or 1. PostgreSQL writes its geometric types as text.
+func mysqlGeometry(val any) bool {
+	b := []byte(stringValue(val))
+	return len(b) >= 9 && b[4] <= 1
+}
+
+// wkbGeometry encodes a geometry of the given WKB type made of pts, in
+// little-endian WKB
+func wkbGeometry(typ uint32, pts [][2]float64) []byte {
+	b := []byte{1}
+	b = binary.LittleEndian.AppendUint32(b, typ)
+	appendPoint := func(p [2]float64) {
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(p[0]))
+		b = binary.LittleEndian.AppendUint64(b, math.Float64bits(p[1]))
+	}
+	switch typ {
+	case 1:
+		appendPoint(pts[0])
+	case 2:
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(pts)))
+		for _, p := range pts {
+			appendPoint(p)
+		}
+	case 3:
+		// One ring, closed by repeating its first point
+		b = binary.LittleEndian.AppendUint32(b, 1)
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(pts)+1))
+		for _, p := range append(pts, pts[0]) {
+			appendPoint(p)
+		}
+	default:
+		// Collections of one member of the matching single type
+		member := map[uint32]uint32{4: 1, 5: 2, 6: 3, 7: 1}[typ]
+		b = binary.LittleEndian.AppendUint32(b, 1)
+		b = append(b, wkbGeometry(member, pts)...)
+	}
+	return b
+}
+
+// geometryValue returns a small shape near a random position: a point,
+// segment, triangle or circle as the column's type requires. MySQL values
+// keep the original's SRID. Coordinates stay within latitude and longitude
+// ranges so they are valid in geographic reference systems too.
+func geometryValue(f *gofakeit.Faker, in *Input) (any, error) {
+	x, y := f.Latitude(), f.Longitude()
+	pts := [][2]float64{{x, y}, {x + 0.001, y}, {x, y + 0.001}}
+	colType := strings.ToLower(in.Column.Type)
+
+	if mysqlGeometry(in.Value) {
+		b := append([]byte(nil), []byte(stringValue(in.Value))[:4]...)
+		typ, ok := wkbTypes[colType]
+		if !ok {
+			typ = 1
+		}
+		return append(b, wkbGeometry(typ, pts)...), nil
+	}
+
+	a, b, c := pgPoint(pts[0][0], pts[0][1]), pgPoint(pts[1][0], pts[1][1]), pgPoint(pts[2][0], pts[2][1])
+	switch colType {
+	case "line":
+		// A line through the point at 45 degrees: x - y + C = 0
+		return "{1,-1," + strconv.FormatFloat(y-x, 'f', -1, 64) + "}", nil
+	case "lseg":
+		return "[" + a + "," + b + "]", nil
+	case "box":
+		return a + "," + pgPoint(x+0.001, y+0.001), nil
+	case "path":
+		return "[" + a + "," + b + "," + c + "]", nil
+	case "polygon":
+		return "(" + a + "," + b + "," + c + ")", nil
+	case "circle":
+		return "<" + a + ",0.001>", nil
+	}
+	return a, nil
+}
+
+// pgArrayElement quotes an array element for a PostgreSQL array literal
+func pgArrayElement(v any) string {
+	if v == nil {
+		return "NULL"
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(stringValue(v)) + `"`
+}
+
+// rewritePGArray replaces each element of a PostgreSQL array literal such
+// as {1,"a b",NULL} or {{1,2},{3,4}} with fn's result, keeping the array's
+// shape. NULL elements are passed to fn as nil.
+func rewritePGArray(s string, fn func(elem any) (any, error)) (string, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "{") {
+		return "", badValue(fmt.Errorf("cannot read %q as an array", s))
+	}
+	var out strings.Builder
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case '{', '}', ',':
+			out.WriteByte(s[i])
+			i++
+			continue
+		case ' ', '\t', '\n':
+			i++
+			continue
+		}
+		var elem strings.Builder
+		quoted := s[i] == '"'
+		if quoted {
+			i++
+		}
+		for ; i < len(s); i++ {
+			ch := s[i]
+			if ch == '\\' && i+1 < len(s) {
+				i++
+				elem.WriteByte(s[i])
+				continue
+			}
+			if quoted && ch == '"' {
+				i++
+				break
+			}
+			if !quoted && (ch == ',' || ch == '}') {
+				break
+			}
+			elem.WriteByte(ch)
+		}
+		var v any = elem.String()
+		if !quoted {
+			v = strings.TrimSpace(elem.String())
+			if strings.EqualFold(v.(string), "NULL") {
+				v = nil
+			}
+		}
+		v, err := fn(v)
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(pgArrayElement(v))
+	}
+	return out.String(), nil
+}
+
+// elementColumn describes the elements of an array column as a column
+func elementColumn(col db.ColumnSchema) db.ColumnSchema {
+	elemType := col.ElementType
+	if elemType == "" {
+		elemType = strings.TrimSuffix(col.Type, "[]")
+	}
+	return db.ColumnSchema{Name: col.Name, Type: elemType, Nullable: true}
+}
+
+// arrayValue replaces each element of a PostgreSQL array with a value
+// generated for the element type, keeping the array's shape and NULLs
+func arrayValue(_ *gofakeit.Faker, in *Input) (any, error) {
+	elemCol := elementColumn(in.Column)
+	n := 0
+	return rewritePGArray(stringValue(in.Value), func(elem any) (any, error) {
+		n++
+		if isEmpty(elem) {
+			return elem, nil
+		}
+		v, err := transform(in.Config, in.state, in.Row, elemCol, config.ColumnRule{}, elem)
+		if err != nil {
+			return nil, fmt.Errorf("array element %d: %w", n, err)
+		}
+		return v, nil
+	})
+}
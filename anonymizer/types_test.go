@@ -0,0 +1,185 @@
+package anonymizer
+
+import (
+	"encoding/binary"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andys/new_names/config"
+	"github.com/andys/new_names/db"
+	"github.com/frankban/quicktest"
+)
+
+// anonymizeTyped anonymizes val in a column of its own, with no rule, so
+// the generator is picked from the column's type
+func anonymizeTyped(c *quicktest.C, col db.ColumnSchema, val any) any {
+	schema := &db.TableSchema{Name: "typed", Columns: []db.ColumnSchema{col}}
+	cfg := &config.Config{AnonymizeFields: map[string][]string{"typed": {col.Name}}}
+	row := &Row{Schema: schema, Data: map[string]any{col.Name: val}}
+	c.Assert(Anonymize(row, cfg), quicktest.IsNil)
+	return row.Data[col.Name]
+}
+
+func TestGuessKind_ByType(t *testing.T) {
+	c := quicktest.New(t)
+	for _, test := range []struct {
+		col  db.ColumnSchema
+		kind string
+	}{
+		{db.ColumnSchema{Type: "tinyint"}, "integer"},
+		{db.ColumnSchema{Type: "interval"}, "interval"},
+		{db.ColumnSchema{Type: "point"}, "geometry"},
+		{db.ColumnSchema{Type: "numeric"}, "decimal"},
+		{db.ColumnSchema{Type: "time with time zone"}, "time"},
+		{db.ColumnSchema{Type: "timestamp(6) with time zone"}, "date_shift"},
+		{db.ColumnSchema{Type: "USER-DEFINED", EnumValues: []string{"a"}}, "enum"},
+		{db.ColumnSchema{Type: "set", EnumValues: []string{"a"}}, "set"},
+		{db.ColumnSchema{Type: "ARRAY", ElementType: "int4"}, "array"},
+		{db.ColumnSchema{Type: "text[]"}, "array"},
+		{db.ColumnSchema{Type: "uuid"}, "uuid"},
+		{db.ColumnSchema{Type: "inet"}, "ipv4"},
+		{db.ColumnSchema{Type: "jsonb"}, "json"},
+		{db.ColumnSchema{Type: "boolean"}, "boolean"},
+		{db.ColumnSchema{Name: "contact_email", Type: "varchar"}, "email"},
+		{db.ColumnSchema{Name: "name", Type: "mood", Logical: db.TypeEnum, EnumValues: []string{"ok"}}, "enum"},
+		{db.ColumnSchema{Name: "points", Type: "custom", Logical: db.TypeInteger}, "integer"},
+		{db.ColumnSchema{Type: "bit", Logical: db.TypeBinary}, "bits"},
+		{db.ColumnSchema{Type: "bit", Logical: db.TypeText}, "bits"},
+	} {
+		c.Assert(guessKind(test.col), quicktest.Equals, test.kind, quicktest.Commentf("%+v", test.col))
+	}
+}
+
+func TestIntegerValue_FitsType(t *testing.T) {
+	c := quicktest.New(t)
+	for i := 0; i < 100; i++ {
+		v := anonymizeTyped(c, db.ColumnSchema{Name: "n", Type: "tinyint"}, int64(5)).(int64)
+		c.Assert(v >= -128 && v <= 127, quicktest.IsTrue, quicktest.Commentf("%d", v))
+
+		v = anonymizeTyped(c, db.ColumnSchema{Name: "n", Type: "smallint", Unsigned: true}, int64(5)).(int64)
+		c.Assert(v >= 0 && v <= 65535, quicktest.IsTrue, quicktest.Commentf("%d", v))
+
+		v = anonymizeTyped(c, db.ColumnSchema{Name: "n", Type: "year"}, int64(1999)).(int64)
+		c.Assert(v >= 1901 && v <= 2155, quicktest.IsTrue, quicktest.Commentf("%d", v))
+
+		v = anonymizeTyped(c, db.ColumnSchema{Name: "n", Type: "serial"}, int64(5)).(int64)
+		c.Assert(v >= 1 && v <= 1<<31-1, quicktest.IsTrue, quicktest.Commentf("%d", v))
+	}
+	_, ok := anonymizeTyped(c, db.ColumnSchema{Name: "n", Type: "bigint", Unsigned: true}, uint64(5)).(uint64)
+	c.Assert(ok, quicktest.IsTrue)
+}
+
+func TestDecimalValue_FitsPrecisionAndScale(t *testing.T) {
+	c := quicktest.New(t)
+	col := db.ColumnSchema{Name: "price", Type: "decimal", Precision: 5, Scale: 2}
+	for i := 0; i < 100; i++ {
+		v := anonymizeTyped(c, col, "-123.45").(string)
+		c.Assert(v, quicktest.Matches, `-\d{1,3}\.\d\d`)
+	}
+
+	// Unconstrained numeric keeps the original's places
+	v := anonymizeTyped(c, db.ColumnSchema{Name: "ratio", Type: "numeric"}, "0.1250").(string)
+	c.Assert(v, quicktest.Matches, `\d\.\d{4}`)
+	v = anonymizeTyped(c, db.ColumnSchema{Name: "cost", Type: "money"}, "$1,204.50").(string)
+	c.Assert(v, quicktest.Matches, `\d{1,6}\.\d\d`)
+}
+
+func TestEnumAndSetValues(t *testing.T) {
+	c := quicktest.New(t)
+	values := []string{"red", "green", "blue"}
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		v := anonymizeTyped(c, db.ColumnSchema{Name: "colour", Type: "enum", EnumValues: values}, "red").(string)
+		c.Assert(values, quicktest.Contains, v)
+		seen[v] = true
+
+		set := anonymizeTyped(c, db.ColumnSchema{Name: "tags", Type: "set", EnumValues: values}, "red,blue").(string)
+		c.Assert(set, quicktest.Matches, `(red)?,?(green)?,?(blue)?`)
+		c.Assert(set, quicktest.Not(quicktest.Equals), "")
+	}
+	c.Assert(seen, quicktest.HasLen, 3)
+}
+
+func TestBooleanTimeAndIntervalValues(t *testing.T) {
+	c := quicktest.New(t)
+	_, ok := anonymizeTyped(c, db.ColumnSchema{Name: "active", Type: "boolean"}, true).(bool)
+	c.Assert(ok, quicktest.IsTrue)
+	n := anonymizeTyped(c, db.ColumnSchema{Name: "active", Type: "bool"}, int64(1)).(int64)
+	c.Assert(n == 0 || n == 1, quicktest.IsTrue)
+
+	// A MySQL BOOLEAN column as the schema loader describes it
+	mysqlBool := db.ColumnSchema{Name: "active", Type: "tinyint", Logical: db.TypeBool, Precision: 3}
+	for _, original := range []any{int64(1), []byte("0")} {
+		n = anonymizeTyped(c, mysqlBool, original).(int64)
+		c.Assert(n == 0 || n == 1, quicktest.IsTrue)
+	}
+
+	tm := anonymizeTyped(c, db.ColumnSchema{Name: "opens", Type: "time"}, "09:30:00").(string)
+	_, err := time.Parse("15:04:05", tm)
+	c.Assert(err, quicktest.IsNil)
+
+	iv := anonymizeTyped(c, db.ColumnSchema{Name: "lease", Type: "interval"}, "3 days").(string)
+	c.Assert(iv, quicktest.Matches, `\d+ days \d\d:\d\d:\d\d`)
+
+	bits := anonymizeTyped(c, db.ColumnSchema{Name: "flags", Type: "bit", MaxLength: 6}, "101010").(string)
+	c.Assert(bits, quicktest.Matches, `[01]{6}`)
+	mysqlBits := anonymizeTyped(c, db.ColumnSchema{Name: "flags", Type: "bit", Precision: 4}, []byte{0x0a}).(int64)
+	c.Assert(mysqlBits >= 0 && mysqlBits < 16, quicktest.IsTrue)
+
+	c.Assert(anonymizeTyped(c, db.ColumnSchema{Name: "id", Type: "uuid"}, "0b4c7e5e-5a54-4b9e-9d34-5f4d3b1b2f6a"),
+		quicktest.Matches, `[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+	c.Assert(anonymizeTyped(c, db.ColumnSchema{Name: "addr", Type: "inet"}, "10.0.0.1"),
+		quicktest.Matches, `\d+\.\d+\.\d+\.\d+`)
+	c.Assert(anonymizeTyped(c, db.ColumnSchema{Name: "nic", Type: "macaddr"}, "08:00:2b:01:02:03"),
+		quicktest.Matches, `([0-9a-f]{2}:){5}[0-9a-f]{2}`)
+}
+
+func TestGeometryValue(t *testing.T) {
+	c := quicktest.New(t)
+	c.Assert(anonymizeTyped(c, db.ColumnSchema{Name: "at", Type: "point"}, "(1.5,2)"),
+		quicktest.Matches, `\(-?[\d.]+,-?[\d.]+\)`)
+	c.Assert(anonymizeTyped(c, db.ColumnSchema{Name: "area", Type: "circle"}, "<(0,0),1>"),
+		quicktest.Matches, `<\(-?[\d.]+,-?[\d.]+\),0\.001>`)
+
+	// MySQL's format: the SRID, then a WKB point
+	original := binary.LittleEndian.AppendUint32(nil, 4326)
+	original = append(original, 1)
+	original = binary.LittleEndian.AppendUint32(original, 1)
+	original = append(original, make([]byte, 16)...)
+	v := anonymizeTyped(c, db.ColumnSchema{Name: "at", Type: "point"}, original).([]byte)
+	c.Assert(v, quicktest.HasLen, 25)
+	c.Assert(v[:9], quicktest.DeepEquals, original[:9])
+	c.Assert(v[9:], quicktest.Not(quicktest.DeepEquals), original[9:])
+
+	poly := anonymizeTyped(c, db.ColumnSchema{Name: "area", Type: "multipolygon"}, original).([]byte)
+	c.Assert(binary.LittleEndian.Uint32(poly[5:]), quicktest.Equals, uint32(6))
+	c.Assert(binary.LittleEndian.Uint32(poly[14:]), quicktest.Equals, uint32(3))
+}
+
+func TestArrayValue_KeepsShapeAndNulls(t *testing.T) {
+	c := quicktest.New(t)
+	v := anonymizeTyped(c, db.ColumnSchema{Name: "scores", Type: "ARRAY", ElementType: "int2"}, `{{1,2},{NULL,4}}`).(string)
+	c.Assert(v, quicktest.Matches, `\{\{"-?\d+","-?\d+"\},\{NULL,"-?\d+"\}\}`)
+	for _, n := range strings.FieldsFunc(v, func(r rune) bool { return strings.ContainsRune(`{}",NUL`, r) }) {
+		i, err := strconv.Atoi(n)
+		c.Assert(err, quicktest.IsNil)
+		c.Assert(i >= -32768 && i <= 32767, quicktest.IsTrue)
+	}
+
+	v = anonymizeTyped(c, db.ColumnSchema{Name: "emails", Type: "ARRAY", ElementType: "text"}, `{"a@example.com","b \"c\""}`).(string)
+	c.Assert(v, quicktest.Matches, `\{"[^"]+@[^"]+","[^"]+@[^"]+"\}`)
+
+	schema := db.TableSchema{Name: "t", Columns: []db.ColumnSchema{{Name: "tags", Type: "text"}}}
+	cfg := &config.Config{ColumnRules: map[string]map[string]config.ColumnRule{"t": {"tags": {Strategy: "array"}}}}
+	c.Assert(Validate(cfg, []db.TableSchema{schema}), quicktest.ErrorMatches, `column t.tags: array strategy needs an array column, not text`)
+}
+
+func TestAnonymizeJSON_WithoutPathsReplacesStrings(t *testing.T) {
+	c := quicktest.New(t)
+	doc := `{"name":"Ann Lee","age":41,"contact":{"email":"ann@example.com","tags":["vip"]},"ok":true}`
+	v := anonymizeTyped(c, db.ColumnSchema{Name: "profile", Type: "jsonb"}, doc).(string)
+	c.Assert(v, quicktest.Matches, `\{"name":"[^"]+","age":41,"contact":\{"email":"[^"]+@[^"]+","tags":\["[^"]+"\]\},"ok":true\}`)
+	c.Assert(strings.Contains(v, "Ann Lee") || strings.Contains(v, "ann@example.com"), quicktest.IsFalse)
+}
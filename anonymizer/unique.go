@@ -0,0 +1,134 @@
+package anonymizer
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/andys/new_names/config"
+	"github.com/andys/new_names/db"
+)
+
+// issuedValues records the values written to each unique column, fakes and
+// the column's source values alike, so that generated values don't collide
+// with each other or with originals that rows keep, and fail on insert. Rows of one table may be anonymized from several
+// goroutines, so access is locked. Nothing is ever evicted: every value of
+// every anonymized unique column is held until the run ends, so memory grows
+// with the size of those columns.
+var issuedValues = struct {
+	sync.Mutex
+	byColumn map[string]map[string]struct{}
+}{byColumn: make(map[string]map[string]struct{})}
+
+// issuedIn returns the values recorded for table.column. The caller holds
+// the lock.
+func issuedIn(table, column string) map[string]struct{} {
+	key := table + "." + column
+	seen, ok := issuedValues.byColumn[key]
+	if !ok {
+		seen = make(map[string]struct{})
+		issuedValues.byColumn[key] = seen
+	}
+	return seen
+}
+
+// UniqueColumns returns the anonymized columns of a table that a unique
+// index covers. Their source values have to be passed to ReserveUnique
+// before the table's rows are anonymized, as some rows keep theirs.
+func UniqueColumns(schema *db.TableSchema, cfg *config.Config) []string {
+	fields := make(map[string]struct{})
+	for _, column := range cfg.AnonymizeFields[schema.Name] {
+		fields[column] = struct{}{}
+	}
+	var columns []string
+	for _, col := range schema.Columns {
+		if _, ok := fields[col.Name]; ok && col.Unique {
+			columns = append(columns, col.Name)
+		}
+	}
+	return columns
+}
+
+// ReserveUnique records a value no fake may take in table.column, such as
+// an original value that a row keeps because its rule's conditions exclude
+// it or the empty policy preserves it
+func ReserveUnique(table, column string, val any) {
+	if val == nil {
+		return
+	}
+	issuedValues.Lock()
+	defer issuedValues.Unlock()
+	issuedIn(table, column)[stringValue(val)] = struct{}{}
+}
+
+// claimUnique returns val if it hasn't been issued for table.column yet, or
+// otherwise val with a discriminator added. Text gets a numeric suffix
+// (before the "@" of an email) that still fits maxLen; integers are bumped
+// to the next free number. Values of other types, such as dates or floats,
+// can't take one, so a repeat of those is an error for the row.
+func claimUnique(table, column string, val any, maxLen int) (any, error) {
+	issuedValues.Lock()
+	defer issuedValues.Unlock()
+	seen := issuedIn(table, column)
+
+	switch v := val.(type) {
+	case []byte:
+		return []byte(claimText(seen, string(v), maxLen)), nil
+	case string:
+		return claimText(seen, v, maxLen), nil
+	case int, int8, int16, int32, int64:
+		return claimInteger(seen, toInt64(v)), nil
+	case uint, uint8, uint16, uint32, uint64:
+		if n := toUint64(v); n <= math.MaxInt64 {
+			return claimInteger(seen, int64(n)), nil
+		}
+	}
+	s := stringValue(val)
+	if _, taken := seen[s]; taken {
+		return nil, badValue(fmt.Errorf("value %s is already used in the unique column", s))
+	}
+	seen[s] = struct{}{}
+	return val, nil
+}
+
+// claimText claims s in seen, or s with the first free discriminator
+func claimText(seen map[string]struct{}, s string, maxLen int) string {
+	v := s
+	for n := 2; ; n++ {
+		if _, taken := seen[v]; !taken {
+			seen[v] = struct{}{}
+			return v
+		}
+		v = withDiscriminator(s, n, maxLen)
+	}
+}
+
+// claimInteger claims n in seen, or the next free number after it
+func claimInteger(seen map[string]struct{}, n int64) int64 {
+	for {
+		s := strconv.FormatInt(n, 10)
+		if _, taken := seen[s]; !taken {
+			seen[s] = struct{}{}
+			return n
+		}
+		n++
+	}
+}
+
+// withDiscriminator appends n to s, or inserts it before the "@" of an email,
+// trimming s so the result is no longer than maxLen characters
+func withDiscriminator(s string, n, maxLen int) string {
+	suffix := strconv.Itoa(n)
+	head, tail := s, ""
+	if at := strings.LastIndex(s, "@"); at > 0 {
+		head, tail = s[:at], s[at:]
+	}
+	for utf8.RuneCountInString(head+suffix+tail) > maxLen && head != "" {
+		_, size := utf8.DecodeLastRuneInString(head)
+		head = head[:len(head)-size]
+	}
+	return head + suffix + tail
+}
@@ -0,0 +1,88 @@
+package anonymizer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/andys/new_names/config"
+	"github.com/andys/new_names/db"
+	"github.com/frankban/quicktest"
+)
+
+func claim(c *quicktest.C, table, column string, val any, maxLen int) any {
+	v, err := claimUnique(table, column, val, maxLen)
+	c.Assert(err, quicktest.IsNil)
+	return v
+}
+
+func TestClaimUnique_AddsDiscriminators(t *testing.T) {
+	c := quicktest.New(t)
+
+	c.Assert(claim(c, "unique_test", "email", "ann@example.com", 100), quicktest.Equals, "ann@example.com")
+	c.Assert(claim(c, "unique_test", "email", "ann@example.com", 100), quicktest.Equals, "ann2@example.com")
+	c.Assert(claim(c, "unique_test", "email", "ann@example.com", 100), quicktest.Equals, "ann3@example.com")
+	// Other columns are tracked separately
+	c.Assert(claim(c, "unique_test", "backup_email", "ann@example.com", 100), quicktest.Equals, "ann@example.com")
+
+	c.Assert(claim(c, "unique_test", "username", "bob", 3), quicktest.Equals, "bob")
+	c.Assert(claim(c, "unique_test", "username", "bob", 3), quicktest.Equals, "bo2")
+
+	c.Assert(claim(c, "unique_test", "badge", int64(7), 0), quicktest.Equals, int64(7))
+	c.Assert(claim(c, "unique_test", "badge", int64(7), 0), quicktest.Equals, int64(8))
+}
+
+func TestClaimUnique_OtherTypes(t *testing.T) {
+	c := quicktest.New(t)
+
+	c.Assert(claim(c, "unique_types", "badge", int32(7), 0), quicktest.Equals, int64(7))
+	c.Assert(claim(c, "unique_types", "badge", uint16(7), 0), quicktest.Equals, int64(8))
+	c.Assert(claim(c, "unique_types", "code", []byte("abc"), 10), quicktest.DeepEquals, []byte("abc"))
+	c.Assert(claim(c, "unique_types", "code", "abc", 10), quicktest.Equals, "abc2")
+
+	day := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	c.Assert(claim(c, "unique_types", "day", day, 0), quicktest.Equals, day)
+	_, err := claimUnique("unique_types", "day", day, 0)
+	c.Assert(err, quicktest.ErrorMatches, `value 2020-01-02T00:00:00Z is already used in the unique column`)
+	c.Assert(err, quicktest.ErrorIs, ErrBadValue)
+}
+
+func TestClaimUnique_AvoidsKeptOriginals(t *testing.T) {
+	c := quicktest.New(t)
+
+	ReserveUnique("unique_kept", "email", []byte("ann@example.com"))
+	ReserveUnique("unique_kept", "badge", int32(7))
+	ReserveUnique("unique_kept", "badge", nil)
+	c.Assert(claim(c, "unique_kept", "email", "ann@example.com", 100), quicktest.Equals, "ann2@example.com")
+	c.Assert(claim(c, "unique_kept", "badge", int64(7), 0), quicktest.Equals, int64(8))
+}
+
+func TestAnonymize_FakesAvoidOriginalsKeptLater(t *testing.T) {
+	c := quicktest.New(t)
+	schema := &db.TableSchema{Name: "unique_later", Columns: []db.ColumnSchema{
+		{Name: "email", Type: "varchar", MaxLength: 100, Unique: true},
+		{Name: "name", Type: "varchar"},
+	}}
+	staff := "staff@example.com"
+	cfg := &config.Config{
+		AnonymizeFields: map[string][]string{"unique_later": {"email", "name"}},
+		ColumnRules: map[string]map[string]config.ColumnRule{"unique_later": {"email": {
+			Strategy: "constant", Value: &staff,
+			Unless: config.Conditions{{Column: "email", Matches: `@example\.com$`}},
+		}}},
+	}
+	c.Assert(UniqueColumns(schema, cfg), quicktest.DeepEquals, []string{"email"})
+
+	// The first pass reserves every source value, including the one the
+	// second row keeps
+	rows := []map[string]any{{"email": "ann@mail.test", "name": "Ann"}, {"email": staff, "name": "Staff"}}
+	for _, data := range rows {
+		ReserveUnique(schema.Name, "email", data["email"])
+	}
+	var got []any
+	for _, data := range rows {
+		row := &Row{Schema: schema, Data: map[string]any{"email": data["email"], "name": data["name"]}}
+		c.Assert(Anonymize(row, cfg), quicktest.IsNil)
+		got = append(got, row.Data["email"])
+	}
+	c.Assert(got, quicktest.DeepEquals, []any{"staff2@example.com", staff})
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
@@ -15,17 +16,206 @@ type Config struct {
 	DestinationURL  string
 	ConfigFile      string
 	Debug           bool
-	Verbose         bool                // Add this line
-	WorkerCount     int                 // Number of workers for reader/writer pools
-	AnonymizeFields map[string][]string `yaml:"-"`
-	SkipTables      []string            // List of tables to skip
-	SampleTables    map[string]float64  // Table name to sample percentage
+	Verbose         bool                             // Add this line
+	WorkerCount     int                              // Number of workers for reader/writer pools
+	AnonymizeFields map[string][]string              `yaml:"-"`
+	ColumnRules     map[string]map[string]ColumnRule `yaml:"-"` // Table name to explicit per-column rules
+	SkipTables      []string                         // List of tables to skip
+	SampleTables    map[string]float64               // Table name to sample percentage
+	ScrubPatterns   map[string]ScrubPattern          // Named patterns for the scrub strategy
+	Locale          string                           // Default locale for fake data, e.g. de or ja
+	TableLocales    map[string]LocaleRule            // Table name to the locale used for its fake data
+	Empty           string                           // Policy for NULL, blank and zero values: preserve (default), replace or fill
+	FillRate        *float64                         // Share of NULLs the fill policy replaces, from 0 to 1
+	Key             string                           // Secret key for deterministic anonymization
+	KeyFile         string                           // File to read Key from, if set
+	TokenKey        []byte                           // AES-SIV key for the tokenize strategy
+	TokenKeyFile    string                           // File to read TokenKey from, hex encoded
+	Seed            *uint64                          // Seed all randomness derives from, for repeatable runs; nil for fresh randomness
+}
+
+// ColumnRule describes how a single column is anonymized
+type ColumnRule struct {
+	Strategy string `yaml:"strategy"` // Generator name; empty means guess from the column name and type
+	Field    string `yaml:"field"`    // Field of a row-level generator, e.g. first_name for person
+
+	When   Conditions `yaml:"when"`   // Only anonymize rows meeting these conditions
+	Unless Conditions `yaml:"unless"` // Leave rows meeting these conditions unchanged
+
+	// Handling of NULL, blank and zero values, overriding the global policy
+	Empty    string   `yaml:"empty"`     // preserve, replace or fill
+	FillRate *float64 `yaml:"fill_rate"` // Share of NULLs the fill policy replaces, from 0 to 1
+
+	Value *string `yaml:"value"` // Replacement for the constant and redact strategies
+
+	// Options for the hash strategy
+	Salt     string `yaml:"salt"`     // HMAC key; defaults to the global key
+	Encoding string `yaml:"encoding"` // Output encoding: hex (default) or base32
+
+	// Options for the date_shift strategy
+	MaxDays int    `yaml:"max_days"` // Largest shift in either direction; defaults to 365
+	Entity  string `yaml:"entity"`   // Column identifying whose dates these are, e.g. user_id
+
+	// Options for the noise strategy
+	Percent float64  `yaml:"percent"` // Largest change in either direction, in percent; defaults to 10
+	Scale   *int     `yaml:"scale"`   // Decimal places to round to; defaults to the original value's
+	Min     *float64 `yaml:"min"`     // Lower clamp for the result
+	Max     *float64 `yaml:"max"`     // Upper clamp for the result
+
+	Template string `yaml:"template"` // Template for the template strategy, e.g. "{{first_name}}.{{last_name}}@example.test"
+
+	Paths map[string]ColumnRule `yaml:"paths"` // JSON path to rule, for the json strategy
+
+	// Locale of the fake data, overriding the table's and the global locale
+	Locale     string `yaml:"locale"`      // Locale name, e.g. de or ja
+	LocaleFrom string `yaml:"locale_from"` // Column holding a country or locale, e.g. country
+
+	// Options for the dictionary strategy
+	File          string `yaml:"file"`          // Text file with one value per line, or CSV file; relative to the config file
+	Map           bool   `yaml:"map"`           // CSV rows map an original value to its replacement
+	Header        bool   `yaml:"header"`        // Skip the first row of a CSV file
+	Deterministic bool   `yaml:"deterministic"` // Replace equal values alike even without a key
+
+	Partition string `yaml:"partition"` // Column within whose values the shuffle strategy permutes, e.g. country
+
+	Bytes int `yaml:"bytes"` // Number of leading bytes the truncate strategy keeps
+
+	// Options for the scrub strategy
+	Patterns []string `yaml:"patterns"` // Patterns to replace; defaults to all built-in patterns
+	Tokens   bool     `yaml:"tokens"`   // Replace matches with deterministic tokens instead of fakes
+
+	Options map[string]any `yaml:"options"` // Options for strategies registered by programs embedding the anonymizer
+
+	// Options for the address strategy, applying to every address column of the row
+	KeepCountry bool `yaml:"keep_country"` // Keep the original country and generate the address there
+	KeepState   bool `yaml:"keep_state"`   // Keep the original state and pick a city in it
+
+	// Options for the mask strategy
+	KeepPrefix int  `yaml:"keep_prefix"` // Leading characters left unmasked
+	KeepSuffix int  `yaml:"keep_suffix"` // Trailing characters left unmasked
+	KeepDomain bool `yaml:"keep_domain"` // Leave an email's domain unmasked
+}
+
+// ScrubPattern is a named regular expression for the scrub strategy, along
+// with the strategy used to replace its matches
+type ScrubPattern struct {
+	Regex    string `yaml:"regex"`
+	Strategy string `yaml:"strategy"` // Defaults to mask
+}
+
+// Condition tests one column of a row, using its value before anonymization.
+// When several tests are given they must all pass.
+type Condition struct {
+	Column  string   `yaml:"column"`
+	Equals  *string  `yaml:"equals"`  // Value equal to this; booleans match 1/0 and t/f too
+	In      []string `yaml:"in"`      // Value equal to one of these
+	Matches string   `yaml:"matches"` // Value matching this regular expression
+	IsNull  *bool    `yaml:"is_null"` // Value is NULL (true) or is not NULL (false)
+}
+
+// Conditions hold when every condition in them holds
+type Conditions []Condition
+
+// UnmarshalYAML accepts either a single condition or a list of them
+func (c *Conditions) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.MappingNode {
+		var cond Condition
+		if err := node.Decode(&cond); err != nil {
+			return err
+		}
+		*c = Conditions{cond}
+		return nil
+	}
+	return node.Decode((*[]Condition)(c))
+}
+
+// LocaleRule picks the locale of a table's fake data, either fixed or read
+// from a column of each row with the fixed locale as a fallback
+type LocaleRule struct {
+	Locale string `yaml:"locale"`
+	From   string `yaml:"from"` // Column holding a country or locale, e.g. country
+}
+
+// UnmarshalYAML accepts either a bare locale name or a mapping
+func (l *LocaleRule) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		l.Locale = strings.TrimSpace(node.Value)
+		return nil
+	}
+	type plain LocaleRule
+	return node.Decode((*plain)(l))
+}
+
+// UnmarshalYAML accepts either a bare strategy name or a mapping of rule options.
+// A bare name may select a field as "strategy.field", e.g. person.first_name.
+func (r *ColumnRule) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		r.Strategy, r.Field, _ = strings.Cut(strings.TrimSpace(node.Value), ".")
+		return nil
+	}
+	// A mapping keyed by JSON paths is shorthand for the json strategy
+	if node.Kind == yaml.MappingNode && len(node.Content) > 0 && strings.HasPrefix(node.Content[0].Value, "$") {
+		r.Strategy = "json"
+		return node.Decode(&r.Paths)
+	}
+	type plain ColumnRule
+	return node.Decode((*plain)(r))
+}
+
+// tableFields is one table in the anonymize section. It is written either as
+// a comma-separated list of columns, or as a mapping of column name to rule.
+type tableFields struct {
+	fields []string
+	rules  map[string]ColumnRule
+}
+
+func (t *tableFields) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		for _, field := range strings.Split(node.Value, ",") {
+			field = strings.TrimSpace(field)
+			if field != "" {
+				t.fields = append(t.fields, field)
+			}
+		}
+		return nil
+	case yaml.MappingNode:
+		t.rules = make(map[string]ColumnRule, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			column := node.Content[i].Value
+			value := node.Content[i+1]
+			var rule ColumnRule
+			if value.ShortTag() == "!!null" {
+				// A written null (null, ~, NULL) selects the null strategy, while
+				// an empty value leaves the strategy to be guessed
+				if value.Value != "" {
+					rule.Strategy = "null"
+				}
+			} else if err := value.Decode(&rule); err != nil {
+				return fmt.Errorf("column %s: %w", column, err)
+			}
+			t.fields = append(t.fields, column)
+			t.rules[column] = rule
+		}
+		return nil
+	}
+	return fmt.Errorf("line %d: expected a comma-separated list or a mapping of columns", node.Line)
+}
+
+// Rule returns the explicit rule for a column, or a zero rule if there is none
+func (c *Config) Rule(table, column string) ColumnRule {
+	return c.ColumnRules[table][column]
 }
 
 type yamlConfig struct {
-	Anonymize map[string]string  `yaml:"anonymize"`
-	Skip      []string           `yaml:"skip"`
-	Sample    map[string]float64 `yaml:"sample"`
+	Anonymize     map[string]tableFields  `yaml:"anonymize"`
+	Skip          []string                `yaml:"skip"`
+	Sample        map[string]float64      `yaml:"sample"`
+	ScrubPatterns map[string]ScrubPattern `yaml:"scrub_patterns"`
+	Locale        string                  `yaml:"locale"`
+	Locales       map[string]LocaleRule   `yaml:"locales"`
+	Empty         string                  `yaml:"empty"`
+	FillRate      *float64                `yaml:"fill_rate"`
 }
 
 // LoadConfig reads and parses the configuration file
@@ -44,19 +234,24 @@ func LoadConfig(cfg *Config, filename string) error {
 	}
 
 	cfg.AnonymizeFields = make(map[string][]string)
-	for table, csvFields := range ycfg.Anonymize {
-		fields := strings.Split(csvFields, ",")
-		fieldList := make([]string, 0, len(fields))
-		for _, field := range fields {
-			field = strings.TrimSpace(field)
-			if field != "" {
-				fieldList = append(fieldList, field)
-			}
+	cfg.ColumnRules = make(map[string]map[string]ColumnRule)
+	for table, tf := range ycfg.Anonymize {
+		fieldList := tf.fields
+		if fieldList == nil {
+			fieldList = []string{}
 		}
 		cfg.AnonymizeFields[table] = fieldList
+		if tf.rules != nil {
+			cfg.ColumnRules[table] = tf.rules
+		}
 	}
 
 	cfg.SkipTables = ycfg.Skip
+	cfg.ScrubPatterns = ycfg.ScrubPatterns
+	cfg.Locale = ycfg.Locale
+	cfg.TableLocales = ycfg.Locales
+	cfg.Empty = ycfg.Empty
+	cfg.FillRate = ycfg.FillRate
 
 	if ycfg.Sample != nil {
 		cfg.SampleTables = make(map[string]float64, len(ycfg.Sample))
@@ -68,3 +263,43 @@ func LoadConfig(cfg *Config, filename string) error {
 	}
 	return nil
 }
+
+// LoadKey reads the anonymization key from cfg.KeyFile, if one is set.
+// Surrounding whitespace is trimmed so keys can be stored with a trailing newline.
+func LoadKey(cfg *Config) error {
+	if cfg.KeyFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to read key file: %w", err)
+	}
+	cfg.Key = strings.TrimSpace(string(data))
+	if cfg.Key == "" {
+		return fmt.Errorf("key file %s is empty", cfg.KeyFile)
+	}
+	return nil
+}
+
+// LoadTokenKey reads the tokenization key from cfg.TokenKeyFile, if one is
+// set. The file holds the key hex encoded, e.g. from `openssl rand -hex 64`.
+func LoadTokenKey(cfg *Config) error {
+	if cfg.TokenKeyFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(cfg.TokenKeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to read token key file: %w", err)
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return fmt.Errorf("token key file %s is not hex encoded: %w", cfg.TokenKeyFile, err)
+	}
+	switch len(key) {
+	case 32, 48, 64:
+	default:
+		return fmt.Errorf("token key in %s must be 32, 48 or 64 bytes, not %d", cfg.TokenKeyFile, len(key))
+	}
+	cfg.TokenKey = key
+	return nil
+}
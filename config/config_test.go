@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/frankban/quicktest"
@@ -54,3 +56,293 @@ func TestLoadConfig_HandlesEmptyFile(t *testing.T) {
 	c.Assert(err, quicktest.IsNil)
 	c.Assert(cfg.AnonymizeFields, quicktest.DeepEquals, map[string][]string{})
 }
+
+func TestLoadKey_ReadsAndTrimsFile(t *testing.T) {
+	c := quicktest.New(t)
+	tmpfile, err := os.CreateTemp("", "testkey*")
+	c.Assert(err, quicktest.IsNil)
+	defer os.Remove(tmpfile.Name())
+	_, err = tmpfile.WriteString("  s3cret\n")
+	c.Assert(err, quicktest.IsNil)
+	tmpfile.Close()
+
+	cfg := &Config{KeyFile: tmpfile.Name()}
+	err = LoadKey(cfg)
+	c.Assert(err, quicktest.IsNil)
+	c.Assert(cfg.Key, quicktest.Equals, "s3cret")
+}
+
+func TestLoadKey_NoFileKeepsKey(t *testing.T) {
+	c := quicktest.New(t)
+	cfg := &Config{Key: "from-env"}
+	err := LoadKey(cfg)
+	c.Assert(err, quicktest.IsNil)
+	c.Assert(cfg.Key, quicktest.Equals, "from-env")
+}
+
+func TestLoadKey_EmptyFile(t *testing.T) {
+	c := quicktest.New(t)
+	tmpfile, err := os.CreateTemp("", "testkey*")
+	c.Assert(err, quicktest.IsNil)
+	defer os.Remove(tmpfile.Name())
+	tmpfile.Close()
+
+	cfg := &Config{KeyFile: tmpfile.Name()}
+	err = LoadKey(cfg)
+	c.Assert(err, quicktest.ErrorMatches, "key file .* is empty")
+}
+
+func TestLoadConfig_ParsesColumnStrategies(t *testing.T) {
+	c := quicktest.New(t)
+	content := `
+anonymize:
+  users: email, name
+  accounts:
+    contact: email
+    username: lorem
+    notes:
+    address:
+      strategy: street_address
+    country:
+      strategy: constant
+      value: XX
+    ssn: null
+    given: person.first_name
+`
+	tmpfile, err := os.CreateTemp("", "testconfig*.conf")
+	c.Assert(err, quicktest.IsNil)
+	defer os.Remove(tmpfile.Name())
+	_, err = tmpfile.WriteString(content)
+	c.Assert(err, quicktest.IsNil)
+	tmpfile.Close()
+
+	cfg := &Config{}
+	err = LoadConfig(cfg, tmpfile.Name())
+	c.Assert(err, quicktest.IsNil)
+	c.Assert(cfg.AnonymizeFields, quicktest.DeepEquals, map[string][]string{
+		"users":    {"email", "name"},
+		"accounts": {"contact", "username", "notes", "address", "country", "ssn", "given"},
+	})
+	c.Assert(cfg.Rule("accounts", "contact").Strategy, quicktest.Equals, "email")
+	c.Assert(cfg.Rule("accounts", "username").Strategy, quicktest.Equals, "lorem")
+	c.Assert(cfg.Rule("accounts", "notes").Strategy, quicktest.Equals, "")
+	c.Assert(cfg.Rule("accounts", "address").Strategy, quicktest.Equals, "street_address")
+	c.Assert(cfg.Rule("accounts", "ssn").Strategy, quicktest.Equals, "null")
+	c.Assert(cfg.Rule("accounts", "given"), quicktest.DeepEquals, ColumnRule{Strategy: "person", Field: "first_name"})
+	c.Assert(*cfg.Rule("accounts", "country").Value, quicktest.Equals, "XX")
+	c.Assert(cfg.Rule("accounts", "contact").Value, quicktest.IsNil)
+	c.Assert(cfg.Rule("users", "email").Strategy, quicktest.Equals, "")
+}
+
+func TestLoadConfig_ParsesTransformerOptions(t *testing.T) {
+	c := quicktest.New(t)
+	content := `
+anonymize:
+  policies:
+    number:
+      strategy: policy_number
+      options:
+        prefix: POL
+        digits: 7
+`
+	tmpfile, err := os.CreateTemp("", "testconfig*.conf")
+	c.Assert(err, quicktest.IsNil)
+	defer os.Remove(tmpfile.Name())
+	_, err = tmpfile.WriteString(content)
+	c.Assert(err, quicktest.IsNil)
+	tmpfile.Close()
+
+	cfg := &Config{}
+	err = LoadConfig(cfg, tmpfile.Name())
+	c.Assert(err, quicktest.IsNil)
+	c.Assert(cfg.Rule("policies", "number"), quicktest.DeepEquals, ColumnRule{
+		Strategy: "policy_number",
+		Options:  map[string]any{"prefix": "POL", "digits": 7},
+	})
+}
+
+func TestLoadConfig_ParsesJSONPathShorthand(t *testing.T) {
+	c := quicktest.New(t)
+	content := `
+anonymize:
+  customers:
+    profile:
+      "$.contact.email": email
+      "$.addresses[*].street":
+        strategy: mask
+        keep_suffix: 3
+`
+	tmpfile, err := os.CreateTemp("", "testconfig*.conf")
+	c.Assert(err, quicktest.IsNil)
+	defer os.Remove(tmpfile.Name())
+	_, err = tmpfile.WriteString(content)
+	c.Assert(err, quicktest.IsNil)
+	tmpfile.Close()
+
+	cfg := &Config{}
+	err = LoadConfig(cfg, tmpfile.Name())
+	c.Assert(err, quicktest.IsNil)
+	c.Assert(cfg.Rule("customers", "profile"), quicktest.DeepEquals, ColumnRule{
+		Strategy: "json",
+		Paths: map[string]ColumnRule{
+			"$.contact.email":       {Strategy: "email"},
+			"$.addresses[*].street": {Strategy: "mask", KeepSuffix: 3},
+		},
+	})
+}
+
+func TestLoadConfig_ParsesScrubPatterns(t *testing.T) {
+	c := quicktest.New(t)
+	content := `
+scrub_patterns:
+  employee_id:
+    regex: 'EMP-\d{6}'
+    strategy: redact
+anonymize:
+  support_tickets:
+    body:
+      strategy: scrub
+      patterns: [email, employee_id]
+      tokens: true
+`
+	tmpfile, err := os.CreateTemp("", "testconfig*.conf")
+	c.Assert(err, quicktest.IsNil)
+	defer os.Remove(tmpfile.Name())
+	_, err = tmpfile.WriteString(content)
+	c.Assert(err, quicktest.IsNil)
+	tmpfile.Close()
+
+	cfg := &Config{}
+	err = LoadConfig(cfg, tmpfile.Name())
+	c.Assert(err, quicktest.IsNil)
+	c.Assert(cfg.ScrubPatterns, quicktest.DeepEquals, map[string]ScrubPattern{
+		"employee_id": {Regex: `EMP-\d{6}`, Strategy: "redact"},
+	})
+	c.Assert(cfg.Rule("support_tickets", "body"), quicktest.DeepEquals, ColumnRule{
+		Strategy: "scrub",
+		Patterns: []string{"email", "employee_id"},
+		Tokens:   true,
+	})
+}
+
+func TestLoadConfig_ParsesLocales(t *testing.T) {
+	c := quicktest.New(t)
+	content := `
+locale: de
+locales:
+  orders: ja
+  customers:
+    locale: de
+    from: country
+anonymize:
+  customers:
+    name:
+      strategy: name
+      locale_from: billing_country
+`
+	tmpfile, err := os.CreateTemp("", "testconfig*.conf")
+	c.Assert(err, quicktest.IsNil)
+	defer os.Remove(tmpfile.Name())
+	_, err = tmpfile.WriteString(content)
+	c.Assert(err, quicktest.IsNil)
+	tmpfile.Close()
+
+	cfg := &Config{}
+	err = LoadConfig(cfg, tmpfile.Name())
+	c.Assert(err, quicktest.IsNil)
+	c.Assert(cfg.Locale, quicktest.Equals, "de")
+	c.Assert(cfg.TableLocales, quicktest.DeepEquals, map[string]LocaleRule{
+		"orders":    {Locale: "ja"},
+		"customers": {Locale: "de", From: "country"},
+	})
+	c.Assert(cfg.Rule("customers", "name"), quicktest.DeepEquals, ColumnRule{Strategy: "name", LocaleFrom: "billing_country"})
+}
+
+func TestLoadConfig_ParsesConditions(t *testing.T) {
+	c := quicktest.New(t)
+	content := `
+anonymize:
+  users:
+    email:
+      strategy: email
+      unless:
+        column: email
+        matches: '@ourcompany\.com$'
+    address:
+      strategy: street_address
+      when:
+        - column: is_business
+          equals: false
+        - column: status
+          in: [active, pending]
+        - column: deleted_at
+          is_null: true
+`
+	tmpfile, err := os.CreateTemp("", "testconfig*.conf")
+	c.Assert(err, quicktest.IsNil)
+	defer os.Remove(tmpfile.Name())
+	_, err = tmpfile.WriteString(content)
+	c.Assert(err, quicktest.IsNil)
+	tmpfile.Close()
+
+	cfg := &Config{}
+	err = LoadConfig(cfg, tmpfile.Name())
+	c.Assert(err, quicktest.IsNil)
+	c.Assert(cfg.Rule("users", "email").Unless, quicktest.DeepEquals, Conditions{
+		{Column: "email", Matches: `@ourcompany\.com$`},
+	})
+	no, yes := "false", true
+	c.Assert(cfg.Rule("users", "address").When, quicktest.DeepEquals, Conditions{
+		{Column: "is_business", Equals: &no},
+		{Column: "status", In: []string{"active", "pending"}},
+		{Column: "deleted_at", IsNull: &yes},
+	})
+}
+
+func TestLoadConfig_ParsesEmptyPolicy(t *testing.T) {
+	c := quicktest.New(t)
+	content := `
+empty: fill
+fill_rate: 0.25
+anonymize:
+  users:
+    phone:
+      strategy: phone
+      empty: replace
+    nickname:
+      fill_rate: 0.5
+`
+	tmpfile, err := os.CreateTemp("", "testconfig*.conf")
+	c.Assert(err, quicktest.IsNil)
+	defer os.Remove(tmpfile.Name())
+	_, err = tmpfile.WriteString(content)
+	c.Assert(err, quicktest.IsNil)
+	tmpfile.Close()
+
+	cfg := &Config{}
+	err = LoadConfig(cfg, tmpfile.Name())
+	c.Assert(err, quicktest.IsNil)
+	c.Assert(cfg.Empty, quicktest.Equals, "fill")
+	c.Assert(*cfg.FillRate, quicktest.Equals, 0.25)
+	c.Assert(cfg.Rule("users", "phone").Empty, quicktest.Equals, "replace")
+	c.Assert(*cfg.Rule("users", "nickname").FillRate, quicktest.Equals, 0.5)
+}
+
+func TestLoadTokenKey(t *testing.T) {
+	c := quicktest.New(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "token.key")
+
+	c.Assert(os.WriteFile(path, []byte(strings.Repeat("ab", 64)+"\n"), 0o600), quicktest.IsNil)
+	cfg := &Config{TokenKeyFile: path}
+	c.Assert(LoadTokenKey(cfg), quicktest.IsNil)
+	c.Assert(cfg.TokenKey, quicktest.HasLen, 64)
+
+	c.Assert(os.WriteFile(path, []byte(strings.Repeat("ab", 16)), 0o600), quicktest.IsNil)
+	c.Assert(LoadTokenKey(cfg), quicktest.ErrorMatches, `token key in .* must be 32, 48 or 64 bytes, not 16`)
+
+	c.Assert(os.WriteFile(path, []byte("not hex"), 0o600), quicktest.IsNil)
+	c.Assert(LoadTokenKey(cfg), quicktest.ErrorMatches, `token key file .* is not hex encoded: .*`)
+
+	c.Assert(LoadTokenKey(&Config{}), quicktest.IsNil)
+}
@@ -2,23 +2,39 @@ package db
 
 import (
 	"fmt"
+	"strings"
 )
 
 // TableSchema represents the structure of a database table
 type TableSchema struct {
-	Name    string
-	Columns []ColumnSchema
-	HasID   bool   // Indicates if table has an ID field for upsert logic
-	IDCol   string // Name of the ID column, if any
+	Name          string
+	Columns       []ColumnSchema
+	HasID         bool          // Indicates if table has an ID field for upsert logic
+	IDCol         string        // Name of the ID column, if any
+	UniqueIndexes []UniqueIndex // Unique indexes and constraints, excluding the primary key
 }
 
 // ColumnSchema represents the structure of a table column
 type ColumnSchema struct {
 	Name      string
-	Type      string
-	IsID      bool // True if this is an ID column
+	Type      string      // Type as the database reports it
+	Logical   LogicalType // Type normalized across databases
+	IsID      bool        // True if this is an ID column
 	Nullable  bool
-	MaxLength int // Maximum length for varchar fields
+	MaxLength int  // Maximum length for varchar fields
+	Unique    bool // True if a single-column unique index covers this column
+
+	EnumValues  []string // Allowed values of enum and set columns, in order
+	ElementType string   // Element type of array columns, e.g. "int4"
+	Precision   int      // Total digits of numeric columns
+	Scale       int      // Digits after the decimal point of numeric columns
+	Unsigned    bool     // True for MySQL unsigned numeric columns
+}
+
+// UniqueIndex represents a unique index or constraint on a table
+type UniqueIndex struct {
+	Name    string
+	Columns []string
 }
 
 // GetSchema retrieves the database schema for all tables
@@ -49,7 +65,11 @@ func (c *Connection) getMySQLSchema() ([]TableSchema, error) {
             c.DATA_TYPE,
             CASE WHEN c.IS_NULLABLE = 'YES' THEN 1 ELSE 0 END as IS_NULLABLE,
             CASE WHEN c.COLUMN_KEY = 'PRI' THEN 1 ELSE 0 END as IS_PRIMARY,
-            COALESCE(c.CHARACTER_MAXIMUM_LENGTH, 0) as MAX_LENGTH
+            COALESCE(c.CHARACTER_MAXIMUM_LENGTH, 0) as MAX_LENGTH,
+            c.COLUMN_TYPE,
+            COALESCE(c.NUMERIC_PRECISION, 0) as NUMERIC_PRECISION,
+            COALESCE(c.NUMERIC_SCALE, 0) as NUMERIC_SCALE,
+            '' as ENUM_VALUES
         FROM information_schema.TABLES t
         JOIN information_schema.COLUMNS c 
             ON t.TABLE_NAME = c.TABLE_NAME AND t.TABLE_SCHEMA = c.TABLE_SCHEMA
@@ -57,7 +77,27 @@ func (c *Connection) getMySQLSchema() ([]TableSchema, error) {
             AND t.TABLE_TYPE = 'BASE TABLE'
         ORDER BY t.TABLE_NAME, c.ORDINAL_POSITION`
 
-	return c.processSchemaRows(query, dbName)
+	schemas, err := c.processSchemaRows(query, dbName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Query to get unique indexes other than the primary key
+	indexQuery := `
+        SELECT 
+            s.TABLE_NAME,
+            s.INDEX_NAME,
+            s.COLUMN_NAME
+        FROM information_schema.STATISTICS s
+        WHERE s.TABLE_SCHEMA = ?
+            AND s.NON_UNIQUE = 0
+            AND s.INDEX_NAME <> 'PRIMARY'
+        ORDER BY s.TABLE_NAME, s.INDEX_NAME, s.SEQ_IN_INDEX`
+
+	if err := c.processUniqueIndexRows(schemas, indexQuery, dbName); err != nil {
+		return nil, err
+	}
+	return schemas, nil
 }
 
 func (c *Connection) getPostgresSchema() ([]TableSchema, error) {
@@ -69,7 +109,16 @@ func (c *Connection) getPostgresSchema() ([]TableSchema, error) {
             c.data_type,
             CASE WHEN c.is_nullable = 'YES' THEN 1 ELSE 0 END as is_nullable,
             CASE WHEN pk.column_name IS NOT NULL THEN 1 ELSE 0 END as is_primary,
-            COALESCE(c.character_maximum_length, 0) as max_length
+            COALESCE(c.character_maximum_length, 0) as max_length,
+            c.udt_name,
+            COALESCE(c.numeric_precision, 0) as numeric_precision,
+            COALESCE(c.numeric_scale, 0) as numeric_scale,
+            COALESCE((
+                SELECT string_agg(e.enumlabel, chr(31) ORDER BY e.enumsortorder)
+                FROM pg_type ty
+                JOIN pg_enum e ON e.enumtypid = ty.oid
+                WHERE ty.typname = c.udt_name
+            ), '') as enum_values
         FROM information_schema.tables t
         JOIN information_schema.columns c 
             ON t.table_name = c.table_name
@@ -85,7 +134,32 @@ func (c *Connection) getPostgresSchema() ([]TableSchema, error) {
             AND t.table_type = 'BASE TABLE'
         ORDER BY t.table_name, c.ordinal_position`
 
-	return c.processSchemaRows(query)
+	schemas, err := c.processSchemaRows(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Query to get unique indexes other than the primary key
+	indexQuery := `
+        SELECT 
+            t.relname AS table_name,
+            i.relname AS index_name,
+            a.attname AS column_name
+        FROM pg_index ix
+        JOIN pg_class t ON t.oid = ix.indrelid
+        JOIN pg_class i ON i.oid = ix.indexrelid
+        JOIN pg_namespace n ON n.oid = t.relnamespace
+        JOIN LATERAL unnest(ix.indkey) WITH ORDINALITY AS k(attnum, ord) ON true
+        JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+        WHERE n.nspname = 'public'
+            AND ix.indisunique
+            AND NOT ix.indisprimary
+        ORDER BY t.relname, i.relname, k.ord`
+
+	if err := c.processUniqueIndexRows(schemas, indexQuery); err != nil {
+		return nil, err
+	}
+	return schemas, nil
 }
 
 func (c *Connection) processSchemaRows(query string, args ...interface{}) ([]TableSchema, error) {
@@ -102,9 +176,11 @@ func (c *Connection) processSchemaRows(query string, args ...interface{}) ([]Tab
 	for rows.Next() {
 		var tableName, columnName, dataType string
 		var isNullable, isPrimary bool
-		var maxLength int
+		var maxLength, precision, scale int
+		var detail, enumValues string
 
-		if err := rows.Scan(&tableName, &columnName, &dataType, &isNullable, &isPrimary, &maxLength); err != nil {
+		if err := rows.Scan(&tableName, &columnName, &dataType, &isNullable, &isPrimary, &maxLength,
+			&detail, &precision, &scale, &enumValues); err != nil {
 			return nil, fmt.Errorf("failed to scan schema row: %w", err)
 		}
 
@@ -127,6 +203,12 @@ func (c *Connection) processSchemaRows(query string, args ...interface{}) ([]Tab
 			IsID:      isPrimary && columnName == "id",
 			Nullable:  isNullable,
 			MaxLength: maxLength,
+			Precision: precision,
+			Scale:     scale,
+		}
+		c.describeColumn(&column, detail, enumValues)
+		if column.Logical == "" {
+			column.Logical = LogicalTypeOf(c.Type, column)
 		}
 
 		if column.IsID {
@@ -148,3 +230,114 @@ func (c *Connection) processSchemaRows(query string, args ...interface{}) ([]Tab
 
 	return schemas, nil
 }
+
+// describeColumn fills in the enum values and array element type of a column,
+// and its logical type where the type name alone doesn't give it. detail is
+// the full column type on MySQL, e.g. "enum('a','b')" or "int unsigned", and
+// the underlying type name on PostgreSQL, e.g. "_int4" for an int4 array.
+// enumValues holds PostgreSQL enum labels separated by the unit separator
+// character.
+func (c *Connection) describeColumn(column *ColumnSchema, detail, enumValues string) {
+	switch c.Type {
+	case MySQL:
+		lower := strings.ToLower(detail)
+		switch {
+		case strings.HasPrefix(lower, "enum(") || strings.HasPrefix(lower, "set("):
+			column.EnumValues = parseMySQLEnumValues(detail)
+		case lower == "tinyint(1)":
+			// BOOLEAN columns, which MySQL reports as tinyint
+			column.Logical = TypeBool
+		case strings.HasSuffix(lower, " unsigned") || strings.Contains(lower, " unsigned "):
+			column.Unsigned = true
+		}
+	case PostgreSQL:
+		if strings.EqualFold(column.Type, "ARRAY") {
+			column.ElementType = strings.TrimPrefix(detail, "_")
+		}
+		if enumValues != "" {
+			column.EnumValues = strings.Split(enumValues, "\x1f")
+		}
+	}
+}
+
+// parseMySQLEnumValues returns the values listed in a MySQL enum or set
+// column type such as "enum('small','it”s big')"
+func parseMySQLEnumValues(columnType string) []string {
+	open, end := strings.IndexByte(columnType, '('), strings.LastIndexByte(columnType, ')')
+	if open < 0 || end < open {
+		return nil
+	}
+	list := columnType[open+1 : end]
+	var values []string
+	var value strings.Builder
+	quoted := false
+	for i := 0; i < len(list); i++ {
+		ch := list[i]
+		switch {
+		case ch == '\'' && quoted && i+1 < len(list) && list[i+1] == '\'':
+			value.WriteByte('\'')
+			i++
+		case ch == '\\' && quoted && i+1 < len(list):
+			value.WriteByte(list[i+1])
+			i++
+		case ch == '\'':
+			if quoted {
+				values = append(values, value.String())
+				value.Reset()
+			}
+			quoted = !quoted
+		case quoted:
+			value.WriteByte(ch)
+		}
+	}
+	return values
+}
+
+// processUniqueIndexRows attaches the unique indexes returned by query to schemas,
+// and marks columns that are unique on their own
+func (c *Connection) processUniqueIndexRows(schemas []TableSchema, query string, args ...interface{}) error {
+	rows, err := c.db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query unique indexes: %w", err)
+	}
+	defer rows.Close()
+
+	tables := make(map[string]*TableSchema, len(schemas))
+	for i := range schemas {
+		tables[schemas[i].Name] = &schemas[i]
+	}
+
+	for rows.Next() {
+		var tableName, indexName, columnName string
+		if err := rows.Scan(&tableName, &indexName, &columnName); err != nil {
+			return fmt.Errorf("failed to scan unique index row: %w", err)
+		}
+		table, ok := tables[tableName]
+		if !ok {
+			continue
+		}
+		n := len(table.UniqueIndexes)
+		if n == 0 || table.UniqueIndexes[n-1].Name != indexName {
+			table.UniqueIndexes = append(table.UniqueIndexes, UniqueIndex{Name: indexName})
+			n++
+		}
+		table.UniqueIndexes[n-1].Columns = append(table.UniqueIndexes[n-1].Columns, columnName)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating unique index rows: %w", err)
+	}
+
+	for _, table := range tables {
+		for _, index := range table.UniqueIndexes {
+			if len(index.Columns) != 1 {
+				continue
+			}
+			for i := range table.Columns {
+				if table.Columns[i].Name == index.Columns[0] {
+					table.Columns[i].Unique = true
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -24,11 +24,25 @@ func TestGetSchema_MySQL(t *testing.T) {
 		WithArgs("testdb").
 		WillReturnRows(sqlmock.NewRows([]string{
 			"TABLE_NAME", "COLUMN_NAME", "DATA_TYPE", "IS_NULLABLE", "IS_PRIMARY", "MAX_LENGTH",
+			"COLUMN_TYPE", "NUMERIC_PRECISION", "NUMERIC_SCALE", "ENUM_VALUES",
 		}).
-			AddRow("users", "id", "int", true, true, 0).
-			AddRow("users", "name", "varchar", false, false, 255).
-			AddRow("posts", "id", "int", true, true, 0).
-			AddRow("posts", "title", "varchar", false, false, 100),
+			AddRow("users", "id", "int", true, true, 0, "int unsigned", 10, 0, "").
+			AddRow("users", "name", "varchar", false, false, 255, "varchar(255)", 0, 0, "").
+			AddRow("posts", "id", "int", true, true, 0, "int", 10, 0, "").
+			AddRow("posts", "title", "varchar", false, false, 100, "varchar(100)", 0, 0, "").
+			AddRow("posts", "status", "enum", false, false, 7, "enum('draft','it''s live')", 0, 0, "").
+			AddRow("posts", "price", "decimal", true, false, 0, "decimal(8,2)", 8, 2, "").
+			AddRow("posts", "published", "tinyint", false, false, 0, "tinyint(1)", 3, 0, "").
+			AddRow("posts", "rank", "tinyint", false, false, 0, "tinyint(4)", 3, 0, ""),
+		)
+
+	// Expect unique index query
+	mock.ExpectQuery("FROM information_schema.STATISTICS").
+		WithArgs("testdb").
+		WillReturnRows(sqlmock.NewRows([]string{"TABLE_NAME", "INDEX_NAME", "COLUMN_NAME"}).
+			AddRow("posts", "posts_title_user", "title").
+			AddRow("posts", "posts_title_user", "id").
+			AddRow("users", "users_name", "name"),
 		)
 
 	conn := &Connection{db: dbMock, Type: MySQL, cfg: &config.Config{}}
@@ -39,7 +53,21 @@ func TestGetSchema_MySQL(t *testing.T) {
 	c.Assert(schemas[0].HasID, quicktest.IsTrue)
 	c.Assert(schemas[0].Columns[0].IsID, quicktest.IsTrue)
 	c.Assert(schemas[0].Columns[1].MaxLength, quicktest.Equals, 255)
+	c.Assert(schemas[0].Columns[1].Unique, quicktest.IsTrue)
+	c.Assert(schemas[0].UniqueIndexes, quicktest.DeepEquals, []UniqueIndex{{Name: "users_name", Columns: []string{"name"}}})
 	c.Assert(schemas[1].Name, quicktest.Equals, "posts")
+	c.Assert(schemas[1].Columns[1].Unique, quicktest.IsFalse)
+	c.Assert(schemas[1].UniqueIndexes, quicktest.DeepEquals, []UniqueIndex{{Name: "posts_title_user", Columns: []string{"title", "id"}}})
+	c.Assert(schemas[0].Columns[0].Unsigned, quicktest.IsTrue)
+	c.Assert(schemas[1].Columns[0].Unsigned, quicktest.IsFalse)
+	c.Assert(schemas[1].Columns[2].EnumValues, quicktest.DeepEquals, []string{"draft", "it's live"})
+	c.Assert(schemas[1].Columns[3].Precision, quicktest.Equals, 8)
+	c.Assert(schemas[1].Columns[3].Scale, quicktest.Equals, 2)
+	c.Assert(schemas[1].Columns[2].Logical, quicktest.Equals, TypeEnum)
+	c.Assert(schemas[1].Columns[3].Logical, quicktest.Equals, TypeDecimal)
+	c.Assert(schemas[1].Columns[4].Type, quicktest.Equals, "tinyint")
+	c.Assert(schemas[1].Columns[4].Logical, quicktest.Equals, TypeBool)
+	c.Assert(schemas[1].Columns[5].Logical, quicktest.Equals, TypeInteger)
 }
 
 func TestGetSchema_PostgreSQL(t *testing.T) {
@@ -51,9 +79,17 @@ func TestGetSchema_PostgreSQL(t *testing.T) {
 	mock.ExpectQuery("FROM information_schema.tables").
 		WillReturnRows(sqlmock.NewRows([]string{
 			"table_name", "column_name", "data_type", "is_nullable", "is_primary", "max_length",
+			"udt_name", "numeric_precision", "numeric_scale", "enum_values",
 		}).
-			AddRow("users", "id", "integer", true, true, 0).
-			AddRow("users", "email", "varchar", false, false, 100),
+			AddRow("users", "id", "integer", true, true, 0, "int4", 32, 0, "").
+			AddRow("users", "email", "varchar", false, false, 100, "varchar", 0, 0, "").
+			AddRow("users", "mood", "USER-DEFINED", true, false, 0, "mood", 0, 0, "sad\x1fok\x1fhappy").
+			AddRow("users", "scores", "ARRAY", true, false, 0, "_int4", 0, 0, ""),
+		)
+
+	mock.ExpectQuery("FROM pg_index").
+		WillReturnRows(sqlmock.NewRows([]string{"table_name", "index_name", "column_name"}).
+			AddRow("users", "users_email_key", "email"),
 		)
 
 	conn := &Connection{db: dbMock, Type: PostgreSQL, cfg: &config.Config{}}
@@ -63,6 +99,19 @@ func TestGetSchema_PostgreSQL(t *testing.T) {
 	c.Assert(schemas[0].Name, quicktest.Equals, "users")
 	c.Assert(schemas[0].Columns[0].IsID, quicktest.IsTrue)
 	c.Assert(schemas[0].Columns[1].MaxLength, quicktest.Equals, 100)
+	c.Assert(schemas[0].Columns[1].Unique, quicktest.IsTrue)
+	c.Assert(schemas[0].Columns[2].EnumValues, quicktest.DeepEquals, []string{"sad", "ok", "happy"})
+	c.Assert(schemas[0].Columns[3].ElementType, quicktest.Equals, "int4")
+	c.Assert(schemas[0].Columns[1].Logical, quicktest.Equals, TypeText)
+	c.Assert(schemas[0].Columns[2].Logical, quicktest.Equals, TypeEnum)
+	c.Assert(schemas[0].Columns[3].Logical, quicktest.Equals, TypeArray)
+}
+
+func TestParseMySQLEnumValues(t *testing.T) {
+	c := quicktest.New(t)
+	c.Assert(parseMySQLEnumValues("set('a','b,c','')"), quicktest.DeepEquals, []string{"a", "b,c", ""})
+	c.Assert(parseMySQLEnumValues(`enum('x\'y')`), quicktest.DeepEquals, []string{"x'y"})
+	c.Assert(parseMySQLEnumValues("int(11)"), quicktest.HasLen, 0)
 }
 
 func TestProcessSchemaRows_QueryError(t *testing.T) {
@@ -85,7 +134,8 @@ func TestProcessSchemaRows_ScanError(t *testing.T) {
 
 	rows := sqlmock.NewRows([]string{
 		"TABLE_NAME", "COLUMN_NAME", "DATA_TYPE", "IS_NULLABLE", "IS_PRIMARY", "MAX_LENGTH",
-	}).AddRow("users", "id", "int", true, true, 0)
+		"COLUMN_TYPE", "NUMERIC_PRECISION", "NUMERIC_SCALE", "ENUM_VALUES",
+	}).AddRow("users", "id", "int", true, true, 0, "int", 10, 0, "")
 	rows.RowError(0, errors.New("scan error"))
 	mock.ExpectQuery("FROM information_schema.TABLES").WillReturnRows(rows)
 
@@ -102,7 +152,8 @@ func TestProcessSchemaRows_RowsErr(t *testing.T) {
 
 	rows := sqlmock.NewRows([]string{
 		"TABLE_NAME", "COLUMN_NAME", "DATA_TYPE", "IS_NULLABLE", "IS_PRIMARY", "MAX_LENGTH",
-	}).AddRow("users", "id", "int", true, true, 0)
+		"COLUMN_TYPE", "NUMERIC_PRECISION", "NUMERIC_SCALE", "ENUM_VALUES",
+	}).AddRow("users", "id", "int", true, true, 0, "int", 10, 0, "")
 	mock.ExpectQuery("FROM information_schema.TABLES").WillReturnRows(rows)
 	// Simulate error after Next
 	rows.RowError(0, errors.New("row error"))
@@ -111,3 +162,21 @@ func TestProcessSchemaRows_RowsErr(t *testing.T) {
 	_, err = conn.processSchemaRows("FROM information_schema.TABLES")
 	c.Assert(err, quicktest.ErrorMatches, "error iterating schema rows: row error")
 }
+
+func TestGetSchema_UniqueIndexQueryError(t *testing.T) {
+	c := quicktest.New(t)
+	dbMock, mock, err := sqlmock.New()
+	c.Assert(err, quicktest.IsNil)
+	defer dbMock.Close()
+
+	mock.ExpectQuery("FROM information_schema.tables").
+		WillReturnRows(sqlmock.NewRows([]string{
+			"table_name", "column_name", "data_type", "is_nullable", "is_primary", "max_length",
+			"udt_name", "numeric_precision", "numeric_scale", "enum_values",
+		}).AddRow("users", "id", "integer", true, true, 0, "int4", 32, 0, ""))
+	mock.ExpectQuery("FROM pg_index").WillReturnError(errors.New("permission denied"))
+
+	conn := &Connection{db: dbMock, Type: PostgreSQL, cfg: &config.Config{}}
+	_, err = conn.GetSchema()
+	c.Assert(err, quicktest.ErrorMatches, "failed to query unique indexes: permission denied")
+}
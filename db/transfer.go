@@ -24,6 +24,25 @@ func (c *Connection) UpsertRow(schema *TableSchema, data map[string]interface{})
 	}
 }
 
+// columnValue prepares a value for writing to col. Drivers return text they
+// have no Go type for as []byte, which PostgreSQL would store as bytea
+// escapes, so only binary and spatial columns are written raw bytes.
+func (c *Connection) columnValue(col ColumnSchema, val interface{}) interface{} {
+	b, ok := val.([]byte)
+	if !ok {
+		return val
+	}
+	logical := col.Logical
+	if logical == "" {
+		logical = LogicalTypeOf(c.Type, col)
+	}
+	switch logical {
+	case TypeBinary, TypeGeometry:
+		return b
+	}
+	return string(b)
+}
+
 func escapeIdentifier(identifier string, dbType DBType) string {
 	switch dbType {
 	case MySQL:
@@ -67,7 +86,7 @@ func (c *Connection) insertRow(schema *TableSchema, data map[string]interface{})
 	for _, col := range schema.Columns {
 		if val, ok := data[col.Name]; ok {
 			columns = append(columns, col.Name)
-			values = append(values, val)
+			values = append(values, c.columnValue(col, val))
 			if c.Type == PostgreSQL {
 				placeholders = append(placeholders, fmt.Sprintf("$%d", len(values)))
 			} else {
@@ -181,7 +200,7 @@ func (c *Connection) mysqlUpsert(schema *TableSchema, data map[string]interface{
 		if val, ok := data[col.Name]; ok {
 			columns = append(columns, col.Name)
 			placeholders = append(placeholders, "?")
-			values = append(values, val)
+			values = append(values, c.columnValue(col, val))
 		}
 	}
 
@@ -246,7 +265,7 @@ func (c *Connection) postgresUpsert(schema *TableSchema, data map[string]interfa
 		if val, ok := data[col.Name]; ok {
 			columns = append(columns, col.Name)
 			placeholders = append(placeholders, fmt.Sprintf("$%d", len(values)+1))
-			values = append(values, val)
+			values = append(values, c.columnValue(col, val))
 			if col.IsID {
 				idColumns = append(idColumns, col.Name)
 			}
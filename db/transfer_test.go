@@ -2,6 +2,7 @@ package db
 
 import (
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	"testing"
 
@@ -117,6 +118,36 @@ func TestMySQLUpsert_SuccessAndNoUpdateClause(t *testing.T) {
 	c.Assert(err, quicktest.IsNil)
 }
 
+func TestMySQLUpsert_WritesBooleans(t *testing.T) {
+	c := quicktest.New(t)
+	dbMock, mock, err := sqlmock.New()
+	c.Assert(err, quicktest.IsNil)
+	defer dbMock.Close()
+
+	conn := &Connection{db: dbMock, Type: MySQL, cfg: &config.Config{}}
+	published := ColumnSchema{Name: "published", Type: "tinyint"}
+	conn.describeColumn(&published, "tinyint(1)", "")
+	schema := &TableSchema{
+		Name:    "posts",
+		HasID:   true,
+		IDCol:   "id",
+		Columns: []ColumnSchema{{Name: "id", Type: "int", IsID: true}, published},
+	}
+
+	// An anonymized value, then one read back as text and kept
+	for _, test := range []struct {
+		val  any
+		want driver.Value
+	}{{int64(1), int64(1)}, {[]byte("0"), "0"}} {
+		mock.ExpectBegin()
+		mock.ExpectExec("SET FOREIGN_KEY_CHECKS=0;").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO `posts`").WithArgs(int64(1), test.want).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		c.Assert(conn.mysqlUpsert(schema, map[string]interface{}{"id": int64(1), "published": test.val}), quicktest.IsNil)
+	}
+	c.Assert(mock.ExpectationsWereMet(), quicktest.IsNil)
+}
+
 func TestMySQLUpsert_ErrorCases(t *testing.T) {
 	c := quicktest.New(t)
 	dbMock, mock, err := sqlmock.New()
@@ -172,6 +203,29 @@ func TestPostgresUpsert_SuccessAndNoUpdateClause(t *testing.T) {
 	c.Assert(err, quicktest.IsNil)
 }
 
+func TestPostgresUpsert_WritesTextBytesAsText(t *testing.T) {
+	c := quicktest.New(t)
+	dbMock, mock, err := sqlmock.New()
+	c.Assert(err, quicktest.IsNil)
+	defer dbMock.Close()
+
+	conn := &Connection{db: dbMock, Type: PostgreSQL, cfg: &config.Config{}}
+	schema := &TableSchema{
+		Name:  "test_table",
+		HasID: true,
+		Columns: []ColumnSchema{
+			{Name: "id", Type: "integer", IsID: true},
+			{Name: "name", Type: "character varying", Logical: TypeText},
+			{Name: "photo", Type: "bytea"},
+		},
+	}
+	data := map[string]interface{}{"id": 1, "name": []byte("foo"), "photo": []byte{0xff, 0xd8}}
+
+	mock.ExpectExec(`INSERT INTO "test_table"`).WithArgs(1, "foo", []byte{0xff, 0xd8}).WillReturnResult(sqlmock.NewResult(1, 1))
+	c.Assert(conn.postgresUpsert(schema, data), quicktest.IsNil)
+	c.Assert(mock.ExpectationsWereMet(), quicktest.IsNil)
+}
+
 func TestPostgresUpsert_Error(t *testing.T) {
 	c := quicktest.New(t)
 	dbMock, mock, err := sqlmock.New()
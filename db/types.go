@@ -0,0 +1,98 @@
+package db
+
+import "strings"
+
+// LogicalType is a column type normalized across MySQL and PostgreSQL, so
+// callers don't have to know each database's type names
+type LogicalType string
+
+const (
+	TypeInteger   LogicalType = "integer"
+	TypeDecimal   LogicalType = "decimal"
+	TypeFloat     LogicalType = "float"
+	TypeText      LogicalType = "text"
+	TypeBinary    LogicalType = "binary"
+	TypeDate      LogicalType = "date"
+	TypeTime      LogicalType = "time"
+	TypeTimestamp LogicalType = "timestamp"
+	TypeJSON      LogicalType = "json"
+	TypeUUID      LogicalType = "uuid"
+	TypeBool      LogicalType = "bool"
+	TypeEnum      LogicalType = "enum"
+	TypeArray     LogicalType = "array"
+	TypeGeometry  LogicalType = "geometry"
+)
+
+// mysqlTypes maps MySQL's DATA_TYPE names to logical types. BOOLEAN columns
+// are reported as tinyint and only told apart by their full column type.
+var mysqlTypes = map[string]LogicalType{
+	"tinyint": TypeInteger, "smallint": TypeInteger, "mediumint": TypeInteger, "int": TypeInteger,
+	"integer": TypeInteger, "bigint": TypeInteger, "year": TypeInteger,
+	"decimal": TypeDecimal, "numeric": TypeDecimal,
+	"float": TypeFloat, "double": TypeFloat, "real": TypeFloat,
+	"char": TypeText, "varchar": TypeText, "tinytext": TypeText, "text": TypeText,
+	"mediumtext": TypeText, "longtext": TypeText,
+	"binary": TypeBinary, "varbinary": TypeBinary, "tinyblob": TypeBinary, "blob": TypeBinary,
+	"mediumblob": TypeBinary, "longblob": TypeBinary, "bit": TypeBinary,
+	"date": TypeDate, "datetime": TypeTimestamp, "timestamp": TypeTimestamp, "time": TypeTime,
+	"json": TypeJSON,
+	"enum": TypeEnum, "set": TypeEnum,
+	"geometry": TypeGeometry, "point": TypeGeometry, "linestring": TypeGeometry, "polygon": TypeGeometry,
+	"multipoint": TypeGeometry, "multilinestring": TypeGeometry, "multipolygon": TypeGeometry,
+	"geometrycollection": TypeGeometry, "geomcollection": TypeGeometry,
+}
+
+// postgresTypes maps PostgreSQL's data_type names, and the short names of
+// the same types, to logical types
+var postgresTypes = map[string]LogicalType{
+	"smallint": TypeInteger, "integer": TypeInteger, "bigint": TypeInteger, "int2": TypeInteger,
+	"int4": TypeInteger, "int8": TypeInteger, "smallserial": TypeInteger, "serial": TypeInteger,
+	"bigserial": TypeInteger, "numeric": TypeDecimal, "decimal": TypeDecimal, "money": TypeDecimal,
+	"real": TypeFloat, "double precision": TypeFloat, "float4": TypeFloat, "float8": TypeFloat,
+	"character varying": TypeText, "character": TypeText, "varchar": TypeText, "char": TypeText,
+	"bpchar": TypeText, "text": TypeText, "citext": TypeText, "name": TypeText, "xml": TypeText,
+	"tsvector": TypeText, "inet": TypeText, "cidr": TypeText, "macaddr": TypeText,
+	"macaddr8": TypeText, "bit": TypeText, "bit varying": TypeText, "varbit": TypeText,
+	"bytea": TypeBinary, "date": TypeDate, "timestamp": TypeTimestamp, "timestamptz": TypeTimestamp,
+	"timestamp without time zone": TypeTimestamp, "timestamp with time zone": TypeTimestamp,
+	"time without time zone": TypeTime, "time with time zone": TypeTime, "time": TypeTime,
+	"timetz": TypeTime, "interval": TypeTime,
+	"json": TypeJSON, "jsonb": TypeJSON, "uuid": TypeUUID, "boolean": TypeBool, "bool": TypeBool,
+	"array": TypeArray,
+	"point": TypeGeometry, "line": TypeGeometry, "lseg": TypeGeometry, "box": TypeGeometry,
+	"path": TypeGeometry, "polygon": TypeGeometry, "circle": TypeGeometry,
+}
+
+// LogicalTypeOf maps a column to its logical type, using the type names of
+// the given database. With no database type, as for columns described by
+// hand, both databases' names are tried. Enum columns need their values to
+// count as enums, and types nothing is known about are treated as text.
+func LogicalTypeOf(dbType DBType, col ColumnSchema) LogicalType {
+	dataType := strings.ToLower(strings.TrimSpace(col.Type))
+	switch {
+	case len(col.EnumValues) > 0:
+		return TypeEnum
+	case col.ElementType != "" || strings.HasSuffix(dataType, "[]"):
+		return TypeArray
+	}
+	// Type modifiers such as the precision in "timestamp(6)" don't change the type
+	if open, end := strings.IndexByte(dataType, '('), strings.IndexByte(dataType, ')'); open > 0 && end > open {
+		dataType = strings.TrimSpace(dataType[:open] + dataType[end+1:])
+	}
+
+	var t LogicalType
+	switch dbType {
+	case MySQL:
+		t = mysqlTypes[dataType]
+	case PostgreSQL:
+		t = postgresTypes[dataType]
+	default:
+		if t = mysqlTypes[dataType]; t == "" {
+			t = postgresTypes[dataType]
+		}
+	}
+	if t == "" || t == TypeEnum {
+		return TypeText
+	}
+	return t
+}
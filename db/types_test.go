@@ -0,0 +1,41 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/frankban/quicktest"
+)
+
+func TestLogicalTypeOf(t *testing.T) {
+	c := quicktest.New(t)
+	for _, test := range []struct {
+		dbType DBType
+		col    ColumnSchema
+		want   LogicalType
+	}{
+		{MySQL, ColumnSchema{Type: "mediumint"}, TypeInteger},
+		{MySQL, ColumnSchema{Type: "longblob"}, TypeBinary},
+		{MySQL, ColumnSchema{Type: "bit"}, TypeBinary},
+		{MySQL, ColumnSchema{Type: "datetime"}, TypeTimestamp},
+		{MySQL, ColumnSchema{Type: "set", EnumValues: []string{"a", "b"}}, TypeEnum},
+		{MySQL, ColumnSchema{Type: "enum"}, TypeText},
+		{MySQL, ColumnSchema{Type: "multipolygon"}, TypeGeometry},
+		{PostgreSQL, ColumnSchema{Type: "point"}, TypeGeometry},
+		{PostgreSQL, ColumnSchema{Type: "interval"}, TypeTime},
+		{PostgreSQL, ColumnSchema{Type: "bit"}, TypeText},
+		{PostgreSQL, ColumnSchema{Type: "timestamp with time zone"}, TypeTimestamp},
+		{PostgreSQL, ColumnSchema{Type: "USER-DEFINED", EnumValues: []string{"sad"}}, TypeEnum},
+		{PostgreSQL, ColumnSchema{Type: "USER-DEFINED"}, TypeText},
+		{PostgreSQL, ColumnSchema{Type: "ARRAY", ElementType: "int4"}, TypeArray},
+		{PostgreSQL, ColumnSchema{Type: "jsonb"}, TypeJSON},
+		{PostgreSQL, ColumnSchema{Type: "uuid"}, TypeUUID},
+		{PostgreSQL, ColumnSchema{Type: "boolean"}, TypeBool},
+		{"", ColumnSchema{Type: "varchar(20)"}, TypeText},
+		{"", ColumnSchema{Type: "timestamp(6) with time zone"}, TypeTimestamp},
+		{"", ColumnSchema{Type: "bytea"}, TypeBinary},
+		{"", ColumnSchema{Type: "text[]"}, TypeArray},
+		{"", ColumnSchema{Type: "tsquery"}, TypeText},
+	} {
+		c.Assert(LogicalTypeOf(test.dbType, test.col), quicktest.Equals, test.want, quicktest.Commentf("%s %s", test.dbType, test.col.Type))
+	}
+}
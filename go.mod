@@ -10,6 +10,7 @@ require (
 	github.com/brianvoe/gofakeit/v7 v7.2.1
 	github.com/frankban/quicktest v1.14.6
 	github.com/go-sql-driver/mysql v1.9.2
+	github.com/google/go-cmp v0.5.9
 	github.com/lib/pq v1.10.9
 	github.com/urfave/cli/v2 v2.27.6
 	golang.org/x/term v0.31.0
@@ -19,7 +20,6 @@ require (
 require (
 	filippo.io/edwards25519 v1.1.0 // indirect
 	github.com/cpuguy83/go-md2man/v2 v2.0.5 // indirect
-	github.com/google/go-cmp v0.5.9 // indirect
 	github.com/kr/pretty v0.3.1 // indirect
 	github.com/kr/text v0.2.0 // indirect
 	github.com/rogpeppe/go-internal v1.9.0 // indirect
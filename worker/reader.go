@@ -1,8 +1,10 @@
 package worker
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"sync/atomic"
 	"time"
 
@@ -63,6 +65,12 @@ func (r *Reader) ProcessTables(schemas []db.TableSchema) error {
 
 		group.SubmitErr(func() error {
 			r.progress.CurrentTable = tableSchema.Name
+			if err := r.loadShuffle(&tableSchema); err != nil {
+				return err
+			}
+			if err := r.loadUnique(&tableSchema); err != nil {
+				return err
+			}
 			var err error
 			if tableSchema.HasID {
 				err = r.processWithId(&tableSchema)
@@ -77,13 +85,87 @@ func (r *Reader) ProcessTables(schemas []db.TableSchema) error {
 	return group.Wait()
 }
 
+// loadShuffle reads the columns a table shuffles in a first pass, so that
+// rows streamed afterwards can draw shuffled values from the whole table
+func (r *Reader) loadShuffle(schema *db.TableSchema) error {
+	deck := anonymizer.NewShuffleDeck(schema, r.cfg)
+	if deck == nil {
+		return nil
+	}
+	columns := deck.Columns()
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(columns, ", "), schema.Name)
+	if schema.HasID {
+		query += " ORDER BY " + schema.IDCol
+	}
+
+	rows, err := r.sourceDB.GetDB().Query(query)
+	if err != nil {
+		return fmt.Errorf("failed to read shuffled columns of table %s: %w", schema.Name, err)
+	}
+	defer rows.Close()
+
+	values := make([]interface{}, len(columns))
+	valuePtrs := make([]interface{}, len(columns))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return fmt.Errorf("failed to scan row from table %s: %w", schema.Name, err)
+		}
+		data := make(map[string]interface{}, len(columns))
+		for i, col := range columns {
+			data[col] = values[i]
+		}
+		deck.Add(data)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read shuffled columns of table %s: %w", schema.Name, err)
+	}
+
+	deck.Shuffle()
+	anonymizer.UseShuffleDeck(deck)
+	return nil
+}
+
+// loadUnique reads the source values of the unique columns a table
+// anonymizes in a first pass, so that no fake takes a value that a row
+// read later keeps
+func (r *Reader) loadUnique(schema *db.TableSchema) error {
+	columns := anonymizer.UniqueColumns(schema, r.cfg)
+	if len(columns) == 0 {
+		return nil
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(columns, ", "), schema.Name)
+
+	rows, err := r.sourceDB.GetDB().Query(query)
+	if err != nil {
+		return fmt.Errorf("failed to read unique columns of table %s: %w", schema.Name, err)
+	}
+	defer rows.Close()
+
+	values := make([]interface{}, len(columns))
+	valuePtrs := make([]interface{}, len(columns))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return fmt.Errorf("failed to scan row from table %s: %w", schema.Name, err)
+		}
+		for i, col := range columns {
+			anonymizer.ReserveUnique(schema.Name, col, values[i])
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read unique columns of table %s: %w", schema.Name, err)
+	}
+	return nil
+}
+
 // process handles reading and processing a single table
 func (r *Reader) processWithoutId(schema *db.TableSchema) error {
 	samplePct, doSample := r.cfg.SampleTables[schema.Name]
-	sampleMod := 1
-	if doSample {
-		sampleMod = int(1 / (samplePct / 100))
-	}
 
 	// Build query to select all rows from table
 	query := fmt.Sprintf("SELECT * FROM %s", schema.Name)
@@ -109,7 +191,6 @@ func (r *Reader) processWithoutId(schema *db.TableSchema) error {
 	for i := range values {
 		valuePtrs[i] = &values[i]
 	}
-	rowCount := 0
 	// Process each row
 	for rows.Next() {
 		if err := rows.Scan(valuePtrs...); err != nil {
@@ -122,20 +203,27 @@ func (r *Reader) processWithoutId(schema *db.TableSchema) error {
 			data[col] = values[i]
 		}
 
-		if rowCount%sampleMod == 0 {
-			// Create row struct
-			row := anonymizer.Row{
-				Schema: schema,
-				Data:   data,
-			}
-
-			// Anonymize the row
-			anonymizer.Anonymize(&row, r.cfg)
+		// Create row struct
+		row := anonymizer.Row{
+			Schema: schema,
+			Data:   data,
+		}
 
-			// Submit to writer
-			r.writer.Submit(row)
+		// Rows come back in no particular order, so sampling is decided per
+		// row rather than by position
+		if !doSample || anonymizer.Sampled(&row, r.cfg, samplePct) {
+			// Anonymize the row and submit it to the writer. A row with a
+			// value that can't be anonymized is counted as an error and skipped.
+			err := anonymizer.Anonymize(&row, r.cfg)
+			switch {
+			case errors.Is(err, anonymizer.ErrBadValue):
+				r.writer.SkipRow(row, err)
+			case err != nil:
+				return fmt.Errorf("failed to anonymize row from table %s: %w", schema.Name, err)
+			default:
+				r.writer.Submit(row)
+			}
 		}
-		rowCount++
 	}
 
 	return rows.Err()
@@ -239,8 +327,16 @@ func (r *Reader) processWithId(schema *db.TableSchema) error {
 			}
 
 			if rowCount < batchWriteSize {
-				anonymizer.Anonymize(&row, r.cfg)
-				r.writer.Submit(row)
+				err := anonymizer.Anonymize(&row, r.cfg)
+				switch {
+				case errors.Is(err, anonymizer.ErrBadValue):
+					r.writer.SkipRow(row, err)
+				case err != nil:
+					rows.Close()
+					return fmt.Errorf("failed to anonymize row from table %s: %w", schema.Name, err)
+				default:
+					r.writer.Submit(row)
+				}
 			}
 			rowCount++
 
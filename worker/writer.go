@@ -66,6 +66,15 @@ func (w *Writer) upsertRow(row anonymizer.Row) error {
 	return err
 }
 
+// SkipRow counts a row with a value that couldn't be anonymized as an
+// error, like a row that failed to write, without writing it
+func (w *Writer) SkipRow(row anonymizer.Row, err error) {
+	w.progress.ErrorCount.Add(1)
+	if w.cfg.Debug {
+		fmt.Fprintf(os.Stderr, "Error anonymizing row of table %s: %v\n", row.Schema.Name, err)
+	}
+}
+
 // GetProgress returns the current progress
 func (w *Writer) GetProgress() WriterProgress {
 	return *w.progress